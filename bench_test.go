@@ -0,0 +1,89 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+// nopLogger is an entryLogger that discards entries, so that benchmarks measure only gaelog's own
+// overhead.
+type nopLogger struct{}
+
+func (nopLogger) Log(e logging.Entry) {}
+
+func (nopLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	return nil
+}
+
+func (nopLogger) Flush() error {
+	return nil
+}
+
+// newBenchmarkLogger returns a Logger like one created for a request on App Engine, but that
+// discards entries.
+func newBenchmarkLogger() *Logger {
+	info, _ := newServiceInfo(defaultConfig)
+	return &Logger{
+		cfg:     defaultConfig,
+		logger:  nopLogger{},
+		monRes:  info.resource,
+		trace:   traceID(testProjectID, "abcdef0123456789"),
+		spanID:  hexSpanID("123"),
+		sampled: true,
+		service: info.service,
+		version: info.version,
+	}
+}
+
+func BenchmarkLogf(b *testing.B) {
+	setGAEEnvVars(b)
+	lg := newBenchmarkLogger()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.Logf(SeverityInfo, "request %d of %d", i, b.N)
+	}
+}
+
+func BenchmarkLogfNoArgs(b *testing.B) {
+	setGAEEnvVars(b)
+	lg := newBenchmarkLogger()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.Logf(SeverityInfo, "request handled")
+	}
+}
+
+func BenchmarkLog(b *testing.B) {
+	setGAEEnvVars(b)
+	lg := newBenchmarkLogger()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.Log(SeverityInfo, "request handled")
+	}
+}
+
+func BenchmarkLogObject(b *testing.B) {
+	setGAEEnvVars(b)
+	lg := newBenchmarkLogger()
+	payload := map[string]interface{}{"bytes": 1024, "cached": true}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lg.Log(SeverityInfo, payload)
+	}
+}
+
+func BenchmarkLogfContext(b *testing.B) {
+	setGAEEnvVars(b)
+	ctx := context.WithValue(context.Background(), ctxKey, newBenchmarkLogger())
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Infof(ctx, "request %d of %d", i, b.N)
+	}
+}
@@ -0,0 +1,62 @@
+package gaelog
+
+import (
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	// BuildRevisionLabel is the key of the label set by WithBuildInfo.
+	BuildRevisionLabel = "build_revision"
+
+	// buildRevisionEnvVar, if set, takes precedence over the revision embedded in the binary.
+	buildRevisionEnvVar = "GAELOG_BUILD_SHA"
+)
+
+var (
+	buildInfoOnce sync.Once
+
+	buildInfoRevision string
+)
+
+// revisionFromBuildInfo returns the VCS revision embedded in the binary by the go command,
+// memoizing the result for use on all but the first call. It returns the empty string if the
+// binary was built without VCS info.
+func revisionFromBuildInfo() string {
+	buildInfoOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				buildInfoRevision = s.Value
+				return
+			}
+		}
+	})
+	return buildInfoRevision
+}
+
+// buildRevision returns the revision to use for the build label: $GAELOG_BUILD_SHA if it's set,
+// otherwise the revision embedded in the binary.
+func buildRevision() string {
+	if sha := os.Getenv(buildRevisionEnvVar); sha != "" {
+		return sha
+	}
+	return revisionFromBuildInfo()
+}
+
+// WithBuildInfo sets a label with key BuildRevisionLabel on every entry logged by the Logger so that
+// entries may be correlated with the build that produced them. Its value is taken from the
+// environment variable GAELOG_BUILD_SHA if it is set, and otherwise from the VCS revision that the
+// go command embeds in binaries built from a repository (see runtime/debug.ReadBuildInfo). If
+// neither is available then the label is omitted.
+func WithBuildInfo() Option {
+	return func(cfg *config) {
+		if rev := buildRevision(); rev != "" {
+			cfg.labels = withLabel(cfg.labels, BuildRevisionLabel, rev)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package gaelog
+
+import (
+	"testing"
+)
+
+func TestWithBuildInfo(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		buildRevisionEnvVar: "0123456789abcdef",
+	})
+	defer unset()
+
+	cfg := newConfig([]Option{WithBuildInfo()})
+	if got := cfg.labels[BuildRevisionLabel]; got != "0123456789abcdef" {
+		t.Errorf("Expected label %q to be %q, got %q", BuildRevisionLabel, "0123456789abcdef", got)
+	}
+}
+
+func TestWithBuildInfoWithoutRevision(t *testing.T) {
+	// Test binaries are built without VCS info, so with the env var unset there is no revision.
+	cfg := newConfig([]Option{WithBuildInfo()})
+	if _, ok := cfg.labels[BuildRevisionLabel]; ok {
+		t.Errorf("Expected no %q label, got %v", BuildRevisionLabel, cfg.labels)
+	}
+}
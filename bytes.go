@@ -0,0 +1,49 @@
+package gaelog
+
+import (
+	"encoding/base64"
+	"unicode/utf8"
+)
+
+// BytesField is the key of the field that holds the base64 encoding of a []byte payload, or of a
+// value made with Bytes. See Logger.Log.
+const BytesField = "base64"
+
+// WithBytesAsText makes the Logger log []byte payloads that are valid UTF-8 as text, as if they
+// had been converted to strings. Payloads that are not valid UTF-8 are still base64-encoded as
+// described in Logger.Log.
+func WithBytesAsText() Option {
+	return func(cfg *config) {
+		cfg.bytesAsText = true
+	}
+}
+
+// bytesPayload returns the payload of an entry for b, as described in Logger.Log.
+func (cfg *config) bytesPayload(b []byte) interface{} {
+	if cfg.bytesAsText && utf8.Valid(b) {
+		return string(b)
+	}
+	return encodeBytes(b)
+}
+
+// fallbackPayload returns v as it should be logged using the standard library's log package when
+// there is no logging client, with a []byte converted as in an entry rather than printed as a list
+// of numbers.
+func (cfg *config) fallbackPayload(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return cfg.bytesPayload(b)
+	}
+	return v
+}
+
+// encodeBytes returns an object whose BytesField field is the base64 encoding of b.
+func encodeBytes(b []byte) map[string]interface{} {
+	return map[string]interface{}{BytesField: base64.StdEncoding.EncodeToString(b)}
+}
+
+// Bytes returns a field with the given key whose value is an object holding the standard base64
+// encoding of b in its BytesField field, so that it's clear from the entry alone how to decode it.
+// Without it, encoding/json encodes a []byte as a bare base64 string.
+func Bytes(key string, b []byte) Field {
+	return Field{Key: key, Value: encodeBytes(b)}
+}
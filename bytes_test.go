@@ -0,0 +1,70 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestLogBytes(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		payload []byte
+		want    interface{}
+	}{
+		{"default", nil, []byte("hi"), map[string]interface{}{BytesField: "aGk="}},
+		{"as_text", []Option{WithBytesAsText()}, []byte("hi"), "hi"},
+		{"as_text_invalid_utf8", []Option{WithBytesAsText()}, []byte{0xff, 0xfe}, map[string]interface{}{BytesField: "//4="}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := &recordingLogger{}
+			lg := &Logger{cfg: newConfig(c.options), logger: rec}
+
+			lg.Info(c.payload)
+
+			if len(rec.payloads) != 1 {
+				t.Fatalf("Expected 1 payload, got %d", len(rec.payloads))
+			}
+			if !reflect.DeepEqual(rec.payloads[0], c.want) {
+				t.Errorf("Expected payload %#v, got %#v", c.want, rec.payloads[0])
+			}
+		})
+	}
+}
+
+func TestLogBytesFallback(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	(&Logger{}).Info([]byte("hi"))
+	(&Logger{cfg: newConfig([]Option{WithBytesAsText()})}).Info([]byte("there"))
+	Log(context.Background(), logging.Info, []byte{1, 2, 3})
+
+	for _, want := range []string{"map[base64:aGk=]", "there", "map[base64:AQID]"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected output to contain %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestBytesField(t *testing.T) {
+	got := Fields(Bytes("digest", []byte{1, 2, 3}), Field{Key: "raw", Value: []byte("hi")})
+
+	want := map[string]interface{}{
+		"digest": map[string]interface{}{BytesField: "AQID"},
+		"raw":    map[string]interface{}{BytesField: "aGk="},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,42 @@
+package gaelog
+
+import (
+	"fmt"
+)
+
+// WithMaxEntriesPerRequest caps the number of entries the Logger logs at n, as a safety valve
+// against a bug, such as logging in a runaway loop, blowing through the logging quota. Once n
+// entries have been logged, further entries are dropped and a single warning is logged noting
+// that the cap was hit. A wrapped handler's Logger is made for each request, so the cap applies
+// per request; entries logged by wrapping, such as the request summary, and by the Loggers derived
+// from the Logger with WithDerivedLogger, Named, and Detach count toward it. A cap of 0, the
+// default, disables it.
+func WithMaxEntriesPerRequest(n int) Option {
+	return func(cfg *config) {
+		cfg.maxEntries = n
+	}
+}
+
+// admit counts an entry toward the cap set with WithMaxEntriesPerRequest and reports whether it
+// is within it. The first time the cap is exceeded it logs a warning saying so.
+func (lg *Logger) admit() bool {
+	max := lg.config().maxEntries
+	if max <= 0 {
+		return true
+	}
+
+	s := lg.requestState()
+	s.mu.Lock()
+	s.entries++
+	n := s.entries
+	s.mu.Unlock()
+
+	if n <= max {
+		return true
+	}
+	if n == max+1 {
+		// Log the warning directly so that it is neither counted nor dropped.
+		lg.logger.Log(lg.entry(SeverityWarning, fmt.Sprintf("gaelog: logged the maximum of %d entries for this request; dropping the rest", max)))
+	}
+	return false
+}
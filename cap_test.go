@@ -0,0 +1,47 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestWithMaxEntriesPerRequest(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMaxEntriesPerRequest(3))
+
+	for i := 0; i < 10; i++ {
+		lg.Infof("entry %d", i)
+	}
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 4 {
+		t.Fatalf("Expected 3 entries and the warning, got %d entries", len(entries))
+	}
+	for i := 0; i < 3; i++ {
+		if want := fmt.Sprintf("entry %d", i); entries[i]["message"] != want {
+			t.Errorf("Expected entry %d to be %q, got %v", i, want, entries[i]["message"])
+		}
+	}
+
+	warning := entries[3]
+	if warning["severity"] != "WARNING" || warning["message"] != "gaelog: logged the maximum of 3 entries for this request; dropping the rest" {
+		t.Errorf("Unexpected warning %v", warning)
+	}
+}
+
+func TestWithMaxEntriesPerRequestDerived(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMaxEntriesPerRequest(2))
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	for i := 0; i < 5; i++ {
+		Infof(WithDerivedLogger(ctx), "entry %d", i)
+	}
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 2 entries and the warning, got %d entries", len(entries))
+	}
+	if entries[2]["severity"] != "WARNING" {
+		t.Errorf("Expected the last entry to be the warning, got %v", entries[2])
+	}
+}
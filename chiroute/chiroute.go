@@ -0,0 +1,26 @@
+// Package chiroute adapts the chi router for use with gaelog.WithRouteLabel.
+package chiroute
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Pattern returns the pattern of the chi route that matched r, such as "/users/{id}", or the
+// empty string if r was not routed by chi. It is a gaelog.RouteFunc:
+//
+//	r := chi.NewRouter()
+//	r.Use(func(h http.Handler) http.Handler {
+//		return gaelog.WrapWithOptions(h, gaelog.WithRouteLabel(chiroute.Pattern))
+//	})
+//
+// chi fills in the pattern as it routes the request, so it is only complete once the request has
+// reached the handler of the matched route.
+func Pattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
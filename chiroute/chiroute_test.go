@@ -0,0 +1,26 @@
+package chiroute
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPattern(t *testing.T) {
+	var got string
+	r := chi.NewRouter()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		got = Pattern(r)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	if got != "/users/{id}" {
+		t.Errorf("Expected %q, got %q", "/users/{id}", got)
+	}
+
+	if got := Pattern(httptest.NewRequest("GET", "/users/42", nil)); got != "" {
+		t.Errorf("Expected no pattern for an unrouted request, got %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package gaelog
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/status"
+)
+
+// WithPartialSuccess sets the partial success flag on the batches of entries written by the
+// underlying Stackdriver Logging logger. Without it, a single invalid entry causes the whole batch
+// to be rejected; with it, the valid entries are written and the failures are reported to the
+// error handler as a *PartialWriteError. See WithErrorHandler.
+func WithPartialSuccess() Option {
+	return func(cfg *config) {
+		cfg.partialSuccess = true
+	}
+}
+
+// WithClientOptions passes the given options through to the underlying Stackdriver Logging
+// client, for customizing how it connects to Cloud Logging, such as with a private endpoint or
+// custom gRPC dial options for VPC Service Controls. They take precedence over the options set by
+// WithEmulatorHost.
+func WithClientOptions(options ...option.ClientOption) Option {
+	return func(cfg *config) {
+		cfg.clientOptions = append(cfg.clientOptions, options...)
+	}
+}
+
+// WithErrorHandler sets the function called with errors that occur when the underlying Stackdriver
+// Logging client writes entries. Entries are written in the background, so such errors cannot be
+// returned by the logging methods. Each Logger not created by a Handler has its own client, which
+// calls the function from its own goroutine, so the function must be safe for concurrent use. It
+// should return quickly. If this option is not given then errors are logged using the standard
+// library's log package.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(cfg *config) {
+		cfg.onError = fn
+	}
+}
+
+// A PartialWriteError is reported to the error handler when a Logger created with
+// WithPartialSuccess writes a batch of entries of which some could not be written.
+type PartialWriteError struct {
+	// Failed maps the index of each entry that could not be written, within its batch, to the
+	// reason it could not be written.
+	Failed map[int]string
+
+	// Err is the error returned by the Stackdriver Logging API.
+	Err error
+}
+
+func (e *PartialWriteError) Error() string {
+	indices := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	reasons := make([]string, len(indices))
+	for j, i := range indices {
+		reasons[j] = fmt.Sprintf("entry %d: %s", i, e.Failed[i])
+	}
+	return fmt.Sprintf("gaelog: %d entries could not be written: %s", len(e.Failed), strings.Join(reasons, "; "))
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// newLoggingClient creates Stackdriver Logging clients. It is a variable so that tests can observe
+// and fail client creation.
+var newLoggingClient = logging.NewClient
+
+// newClient creates a Stackdriver Logging client whose errors are handled as cfg specifies.
+func (cfg *config) newClient(parent string) (*logging.Client, error) {
+	var options []option.ClientOption
+	if cfg.emulatorHost != "" {
+		options = emulatorClientOptions(cfg.emulatorHost)
+	}
+	options = append(options, cfg.clientOptions...)
+
+	client, err := newLoggingClient(cfg.clientContext, parent, options...)
+	if err != nil {
+		return nil, err
+	}
+	client.OnError = cfg.handleClientError
+	return client, nil
+}
+
+// clientLogger returns a logger from client for the log with the given ID, configured as cfg
+// specifies.
+func (cfg *config) clientLogger(client *logging.Client, logID string) *logging.Logger {
+	options := cfg.loggerOptions
+	if cfg.partialSuccess {
+		// Use a full slice expression so that the shared cfg.loggerOptions isn't appended to.
+		options = append(options[:len(options):len(options)], logging.PartialSuccess())
+	}
+	return client.Logger(logID, options...)
+}
+
+func (cfg *config) handleClientError(err error) {
+	err = partialWriteError(err)
+	if cfg.onError != nil {
+		cfg.onError(err)
+		return
+	}
+	log.Printf("gaelog: logging client: %v", err)
+}
+
+// partialWriteError returns a *PartialWriteError if err describes entries that could not be
+// written, or err unchanged otherwise.
+func partialWriteError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, d := range s.Details() {
+		partial, ok := d.(*loggingpb.WriteLogEntriesPartialErrors)
+		if !ok {
+			continue
+		}
+
+		failed := make(map[int]string, len(partial.GetLogEntryErrors()))
+		for i, s := range partial.GetLogEntryErrors() {
+			failed[int(i)] = s.GetMessage()
+		}
+		return &PartialWriteError{Failed: failed, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/kylelemons/godebug/pretty"
+	"google.golang.org/api/option"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPartialWriteError(t *testing.T) {
+	s, err := status.New(codes.InvalidArgument, "some entries are invalid").WithDetails(&loggingpb.WriteLogEntriesPartialErrors{
+		LogEntryErrors: map[int32]*statuspb.Status{
+			3: {Code: int32(codes.InvalidArgument), Message: "payload too large"},
+			1: {Code: int32(codes.InvalidArgument), Message: "bad label"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := partialWriteError(s.Err())
+
+	var pwe *PartialWriteError
+	if !errors.As(got, &pwe) {
+		t.Fatalf("Expected a *PartialWriteError, got %T", got)
+	}
+	want := map[int]string{1: "bad label", 3: "payload too large"}
+	if diff := pretty.Compare(want, pwe.Failed); diff != "" {
+		t.Errorf("Unexpected failed entries. Diff (-want +got):\n%s", diff)
+	}
+
+	wantMsg := "gaelog: 2 entries could not be written: entry 1: bad label; entry 3: payload too large"
+	if got.Error() != wantMsg {
+		t.Errorf("Expected %q, got %q", wantMsg, got.Error())
+	}
+
+	if status.Code(errors.Unwrap(got)) != codes.InvalidArgument {
+		t.Errorf("Expected the API error to be unwrappable, got %v", errors.Unwrap(got))
+	}
+}
+
+func TestPartialWriteErrorOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		errors.New("some error"),
+		status.Error(codes.Unavailable, "try again"),
+	} {
+		if got := partialWriteError(err); got != err {
+			t.Errorf("Expected %v unchanged, got %v", err, got)
+		}
+	}
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	var got error
+	cfg := newConfig([]Option{WithErrorHandler(func(err error) { got = err })})
+
+	want := errors.New("some error")
+	cfg.handleClientError(want)
+	if got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWithClientOptions(t *testing.T) {
+	var got []option.ClientOption
+	newLoggingClient = func(ctx context.Context, parent string, opts ...option.ClientOption) (*logging.Client, error) {
+		got = opts
+		return nil, errors.New("not creating a client")
+	}
+	defer func() { newLoggingClient = logging.NewClient }()
+
+	endpoint := option.WithEndpoint("private.googleapis.com:443")
+	userAgent := option.WithUserAgent("my-app")
+	cfg := newConfig([]Option{WithEmulatorHost(""), WithClientOptions(endpoint), WithClientOptions(userAgent)})
+	if _, err := cfg.newClient("projects/" + testProjectID); err == nil {
+		t.Fatal("Expected the error from the client constructor")
+	}
+
+	want := []option.ClientOption{endpoint, userAgent}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the client constructor to get options %v, got %v", want, got)
+	}
+}
+
+func TestNewClientError(t *testing.T) {
+	setGAEEnvVars(t)
+	newLoggingClient = func(ctx context.Context, parent string, opts ...option.ClientOption) (*logging.Client, error) {
+		return nil, errors.New("no credentials")
+	}
+	defer func() { newLoggingClient = logging.NewClient }()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	lg, err := New(r)
+	if err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Errorf("Expected the client constructor's error, got %v", err)
+	}
+	if lg == nil {
+		t.Fatal("Expected non-nil Logger")
+	}
+	defer lg.Close()
+
+	if !lg.IsFallback() {
+		t.Errorf("Expected fallback Logger")
+	}
+	lg.Infof("hello %d", 1)
+	if !strings.Contains(buf.String(), "hello 1") {
+		t.Errorf("Expected the entry to be logged with the log package, got %q", buf.String())
+	}
+}
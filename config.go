@@ -0,0 +1,89 @@
+package gaelog
+
+import (
+	"net/http"
+
+	"cloud.google.com/go/logging"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// Config is a declarative alternative to Options for apps that build their logging configuration
+// as a whole, for example from a configuration file or environment variables. The zero value of
+// each field leaves the corresponding setting at its default. See the Option named in each field's
+// comment for details.
+type Config struct {
+	// LogID is the log ID. See WithLogID.
+	LogID string
+
+	// ProjectID is the project that entries are logged to and attributed to. See WithProjectID.
+	ProjectID string
+
+	// LogProjectID is the project that entries are written to. See WithLogProjectID.
+	LogProjectID string
+
+	// Resource is the monitored resource of entries. See WithResource.
+	Resource *monitoredres.MonitoredResource
+
+	// MinSeverity is the severity below which entries are discarded. See WithMinSeverity.
+	MinSeverity Severity
+
+	// Labels are set on every entry. See WithLabels.
+	Labels map[string]string
+
+	// Encoder, if set, writes entries to stdout rather than sending them to Stackdriver Logging.
+	// See WithEncoder.
+	Encoder Encoder
+
+	// LazyClient makes creating a Logger non-blocking. See WithLazyClient.
+	LazyClient bool
+
+	// MaxPayloadSize is the size above which payloads are truncated. See WithMaxPayloadSize.
+	MaxPayloadSize int
+
+	// LoggerOptions are passed through to the underlying Stackdriver Logging logger. See
+	// WithLoggerOptions.
+	LoggerOptions []logging.LoggerOption
+}
+
+// Options returns the Options equivalent to c, for use with functions that take Options, such as
+// WrapWithOptions and Configure. Options may be appended to those returned to configure
+// behavior that Config doesn't cover.
+func (c Config) Options() []Option {
+	var options []Option
+	if c.LogID != "" {
+		options = append(options, WithLogID(c.LogID))
+	}
+	if c.ProjectID != "" {
+		options = append(options, WithProjectID(c.ProjectID))
+	}
+	if c.LogProjectID != "" {
+		options = append(options, WithLogProjectID(c.LogProjectID))
+	}
+	if c.Resource != nil {
+		options = append(options, WithResource(c.Resource))
+	}
+	if c.MinSeverity != SeverityDefault {
+		options = append(options, WithMinSeverity(c.MinSeverity))
+	}
+	if len(c.Labels) > 0 {
+		options = append(options, WithLabels(c.Labels))
+	}
+	if c.Encoder != nil {
+		options = append(options, WithEncoder(c.Encoder))
+	}
+	if c.LazyClient {
+		options = append(options, WithLazyClient())
+	}
+	if c.MaxPayloadSize > 0 {
+		options = append(options, WithMaxPayloadSize(c.MaxPayloadSize))
+	}
+	if len(c.LoggerOptions) > 0 {
+		options = append(options, WithLoggerOptions(c.LoggerOptions...))
+	}
+	return options
+}
+
+// NewWithConfig is like NewWithOptions but is configured using a Config.
+func NewWithConfig(r *http.Request, c Config) (*Logger, error) {
+	return NewWithOptions(r, c.Options()...)
+}
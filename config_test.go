@@ -0,0 +1,82 @@
+package gaelog
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	"github.com/kylelemons/godebug/pretty"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+func TestConfigOptions(t *testing.T) {
+	if options := (Config{}).Options(); len(options) != 0 {
+		t.Errorf("Expected no Options for the zero Config, got %d", len(options))
+	}
+
+	resource := &monitoredres.MonitoredResource{Type: "global"}
+	c := Config{
+		LogID:          "my_log",
+		ProjectID:      "my-project",
+		LogProjectID:   "central-logs",
+		Resource:       resource,
+		MinSeverity:    SeverityNotice,
+		Labels:         map[string]string{"a": "b"},
+		Encoder:        ECSEncoder,
+		LazyClient:     true,
+		MaxPayloadSize: 1024,
+	}
+	cfg := newConfig(c.Options())
+
+	got := Config{
+		LogID:          cfg.logID,
+		ProjectID:      cfg.projectID,
+		LogProjectID:   cfg.logProjectID,
+		Resource:       cfg.resource,
+		MinSeverity:    cfg.minSeverity,
+		Labels:         cfg.labels,
+		Encoder:        cfg.encoder,
+		LazyClient:     cfg.lazyClient,
+		MaxPayloadSize: cfg.maxPayloadSize,
+	}
+	if diff := pretty.Compare(got, c); diff != "" {
+		t.Errorf("Unexpected config (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewWithConfig(t *testing.T) {
+	setGAEEnvVars(t)
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	var buf bytes.Buffer
+	resource := &monitoredres.MonitoredResource{Type: "global", Labels: map[string]string{"project_id": testProjectID}}
+	lg, err := NewWithConfig(r, Config{
+		Resource:      resource,
+		MinSeverity:   SeverityInfo,
+		Labels:        map[string]string{"a": "b"},
+		LoggerOptions: []logging.LoggerOption{logging.RedirectAsJSON(&buf)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer lg.Close()
+
+	if lg.monRes != resource {
+		t.Errorf("Expected the given resource, got %v", lg.monRes)
+	}
+
+	lg.Debug("debug")
+	lg.Info("info")
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	labels, _ := entries[0]["logging.googleapis.com/labels"].(map[string]interface{})
+	if labels["a"] != "b" {
+		t.Errorf("Expected label a=b, got %v", labels)
+	}
+}
@@ -0,0 +1,57 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithContextFields makes the package-level logging functions (Logf, Log, and so on) label each
+// entry with the values that the context they are called with holds for the given keys, such as
+// those stashed by authentication or multi-tenancy middleware, without passing them at each call:
+//
+//	gaelog.WithContextFields([]interface{}{auth.SubjectKey, tenantKey}, map[interface{}]string{
+//		auth.SubjectKey: "subject",
+//	})
+//
+// Each label's key is the one given for the context key in rename or, if there is none, the context
+// key formatted as by fmt.Sprint. Values are formatted as by Label. Keys for which the context holds
+// no value are skipped, as are labels that the entry already has, such as those set with WithLabels.
+func WithContextFields(keys []interface{}, rename map[interface{}]string) Option {
+	return func(cfg *config) {
+		cfg.contextFields = nil
+		for _, key := range keys {
+			name, ok := rename[key]
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			cfg.contextFields = append(cfg.contextFields, contextField{key: key, name: name})
+		}
+	}
+}
+
+// contextField is a context key given to WithContextFields and the key of the label it becomes.
+type contextField struct {
+	key  interface{}
+	name string
+}
+
+// applyContextFields labels e with the values of ctx for the keys given to WithContextFields.
+func (cfg *config) applyContextFields(ctx context.Context, e *logging.Entry) {
+	var labels map[string]string
+	for _, f := range cfg.contextFields {
+		if _, ok := e.Labels[f.name]; ok {
+			continue
+		}
+		if v := ctx.Value(f.key); v != nil {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[f.name] = formatLabelValue(v)
+		}
+	}
+	if labels != nil {
+		e.Labels = mergeLabels(e.Labels, labels)
+	}
+}
@@ -0,0 +1,42 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+)
+
+type testCtxKey string
+
+func TestWithContextFields(t *testing.T) {
+	rec := &entryRecorder{}
+	lg := &Logger{
+		cfg: newConfig([]Option{
+			WithContextFields(
+				[]interface{}{testCtxKey("subject"), testCtxKey("tenant"), testCtxKey("shard"), testCtxKey("missing")},
+				map[interface{}]string{testCtxKey("tenant"): "tenant_id"},
+			),
+		}),
+		logger: sinkLogger{rec},
+		labels: map[string]string{"shard": "1"},
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	ctx = context.WithValue(ctx, testCtxKey("subject"), "user-42")
+	ctx = context.WithValue(ctx, testCtxKey("tenant"), 7)
+	ctx = context.WithValue(ctx, testCtxKey("shard"), "2")
+	Info(ctx, "hello")
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	labels := rec.entries[0].Labels
+	want := map[string]string{"subject": "user-42", "tenant_id": "7", "shard": "1"}
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("Expected label %s to be %q, got %q", k, v, labels[k])
+		}
+	}
+	if _, ok := labels["missing"]; ok {
+		t.Errorf("Expected no label for a key without a value")
+	}
+}
@@ -0,0 +1,119 @@
+package gaelog
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// DefaultCorrelationIDHeader is a common choice of header for WithCorrelationID.
+	DefaultCorrelationIDHeader = "X-Correlation-ID"
+
+	// CorrelationIDLabel is the key of the label set by WithCorrelationID.
+	CorrelationIDLabel = "correlation_id"
+)
+
+const correlationIDKey = ctxKeyType("gaelog-correlation-id")
+
+// WithCorrelationID makes the Logger propagate a correlation ID, a stable ID that follows a unit of
+// work across services, in the request header with the given name, such as
+// DefaultCorrelationIDHeader. Unlike the trace, the ID is chosen by the app rather than by Google's
+// infrastructure, so it can be carried by services and queues that don't propagate trace context.
+//
+// The ID is read from the request's header or, if it has none, generated with the Logger's
+// IDGenerator. Every entry is labeled with it with key CorrelationIDLabel. A wrapped handler also
+// sets it as the response's header and stores it in the request's context, from which
+// CorrelationID returns it and OutboundInjector adds it to downstream requests.
+func WithCorrelationID(header string) Option {
+	return func(cfg *config) {
+		cfg.correlationHeader = header
+	}
+}
+
+// correlationID returns r's correlation ID, generating one if r has none. It returns the empty
+// string if the config doesn't call for one.
+func (cfg *config) correlationID(r *http.Request) string {
+	if cfg.correlationHeader == "" {
+		return ""
+	}
+	if id := r.Header.Get(cfg.correlationHeader); id != "" {
+		return id
+	}
+	if cfg.idGenerator != nil {
+		return cfg.idGenerator.NewID(16)
+	}
+	return RandomIDGenerator.NewID(16)
+}
+
+// propagateCorrelationID sets the request's correlation ID, if any, as the response's header and
+// returns a copy of ctx that carries it.
+func (lg *Logger) propagateCorrelationID(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	cfg := lg.config()
+	id := lg.correlationID
+	if id == "" {
+		// The Logger has fallen back, but the ID is still worth passing on.
+		id = cfg.correlationID(r)
+	}
+	if id == "" {
+		return ctx
+	}
+
+	w.Header().Set(cfg.correlationHeader, id)
+	return ContextWithCorrelationID(ctx, id)
+}
+
+// ContextWithCorrelationID returns a copy of ctx that carries the given correlation ID, for
+// propagating one with OutboundInjector from code not run by a wrapped handler, such as a
+// background job.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, as set by a handler wrapped with
+// WithCorrelationID or by ContextWithCorrelationID. It returns the empty string if there is none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
+
+// OutboundInjector is an http.RoundTripper that adds the correlation ID carried by each request's
+// context, if any, to the request as the header with the given name, for passing it on to
+// downstream services:
+//
+//	client := &http.Client{Transport: &gaelog.OutboundInjector{Header: gaelog.DefaultCorrelationIDHeader}}
+//	req, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+//	resp, err := client.Do(req)
+//
+// Requests that already have the header are sent unchanged.
+type OutboundInjector struct {
+	// Header is the name of the header to set. If it is empty then DefaultCorrelationIDHeader is
+	// used.
+	Header string
+
+	// Base is the RoundTripper that sends requests. If it is nil then http.DefaultTransport is
+	// used.
+	Base http.RoundTripper
+}
+
+// RoundTrip sends req with its context's correlation ID added.
+func (t *OutboundInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	header := t.Header
+	if header == "" {
+		header = DefaultCorrelationIDHeader
+	}
+
+	id := CorrelationID(req.Context())
+	if id == "" || req.Header.Get(header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	req.Header.Set(header, id)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,119 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithCorrelationID(t *testing.T) {
+	cases := []struct {
+		name    string
+		inbound string
+		want    string
+	}{
+		{"inbound", "order-1234", "order-1234"},
+		{"generated", "", "abababababababababababababababab"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+
+			var gotCtx string
+			rec := &entryRecorder{}
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotCtx = CorrelationID(r.Context())
+				Infof(r.Context(), "hello")
+			}), WithCorrelationID(DefaultCorrelationIDHeader), WithIDGenerator(NewReaderIDGenerator(bytes.NewReader(bytes.Repeat([]byte{0xab}, 16)))), WithSink(rec))
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			if c.inbound != "" {
+				req.Header.Set(DefaultCorrelationIDHeader, c.inbound)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if gotCtx != c.want {
+				t.Errorf("Expected context to carry %q, got %q", c.want, gotCtx)
+			}
+			if got := w.Result().Header.Get(DefaultCorrelationIDHeader); got != c.want {
+				t.Errorf("Expected response header %q, got %q", c.want, got)
+			}
+			if len(rec.entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+			}
+			if got := rec.entries[0].Labels[CorrelationIDLabel]; got != c.want {
+				t.Errorf("Expected label %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestWithCorrelationIDFallback(t *testing.T) {
+	// No env vars are set, so the Logger falls back, but the ID is still propagated.
+	var gotCtx string
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCtx = CorrelationID(r.Context())
+	}), WithCorrelationID(DefaultCorrelationIDHeader))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(DefaultCorrelationIDHeader, "order-1234")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotCtx != "order-1234" {
+		t.Errorf("Expected context to carry %q, got %q", "order-1234", gotCtx)
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper that calls the function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestOutboundInjector(t *testing.T) {
+	cases := []struct {
+		name     string
+		ctxID    string
+		existing string
+		want     string
+	}{
+		{"injected", "order-1234", "", "order-1234"},
+		{"no_id", "", "", ""},
+		{"already_set", "order-1234", "order-5678", "order-5678"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			inj := &OutboundInjector{Base: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				got = r.Header.Get(DefaultCorrelationIDHeader)
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})}
+
+			ctx := context.Background()
+			if c.ctxID != "" {
+				ctx = ContextWithCorrelationID(ctx, c.ctxID)
+			}
+			req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+			if c.existing != "" {
+				req.Header.Set(DefaultCorrelationIDHeader, c.existing)
+			}
+
+			if _, err := inj.RoundTrip(req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("Expected header %q, got %q", c.want, got)
+			}
+			if c.existing == "" && req.Header.Get(DefaultCorrelationIDHeader) != "" {
+				t.Errorf("Expected the original request to be unmodified")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+
+	"cloud.google.com/go/logging"
+)
+
+const entryDefaultsKey = ctxKeyType("gaelog-entry-defaults")
+
+// WithEntryDefaults returns a copy of ctx carrying defaults for the entries logged by the
+// package-level logging functions (Logf, Log, and so on) when called with the returned context.
+// Each field of defaults is used for entries that would otherwise leave it unset, so values set
+// by the Logger or by the call take precedence; labels are merged, with the entry's own labels
+// taking precedence. The Timestamp, Severity, and Payload fields of defaults are ignored.
+//
+// If ctx already carries defaults then the given defaults are merged into them in the same way,
+// with the given defaults taking precedence.
+func WithEntryDefaults(ctx context.Context, defaults logging.Entry) context.Context {
+	if existing, ok := EntryDefaults(ctx); ok {
+		applyEntryDefaults(&defaults, existing)
+	}
+	return context.WithValue(ctx, entryDefaultsKey, defaults)
+}
+
+// EntryDefaults returns the entry defaults carried by ctx, as set by WithEntryDefaults, and whether
+// there are any.
+func EntryDefaults(ctx context.Context) (logging.Entry, bool) {
+	defaults, ok := ctx.Value(entryDefaultsKey).(logging.Entry)
+	return defaults, ok
+}
+
+// applyEntryDefaults sets each unset field of e to its value in defaults.
+func applyEntryDefaults(e *logging.Entry, defaults logging.Entry) {
+	if len(defaults.Labels) > 0 {
+		e.Labels = mergeLabels(defaults.Labels, e.Labels)
+	}
+	if e.InsertID == "" {
+		e.InsertID = defaults.InsertID
+	}
+	if e.HTTPRequest == nil {
+		e.HTTPRequest = defaults.HTTPRequest
+	}
+	if e.Operation == nil {
+		e.Operation = defaults.Operation
+	}
+	if e.LogName == "" {
+		e.LogName = defaults.LogName
+	}
+	if e.Resource == nil {
+		e.Resource = defaults.Resource
+	}
+	if e.Trace == "" {
+		e.Trace = defaults.Trace
+	}
+	if e.SpanID == "" {
+		e.SpanID = defaults.SpanID
+	}
+	if !e.TraceSampled {
+		e.TraceSampled = defaults.TraceSampled
+	}
+	if e.SourceLocation == nil {
+		e.SourceLocation = defaults.SourceLocation
+	}
+}
+
+// writeContext sends the entry to Stackdriver Logging after applying any entry defaults, context
+// fields, prefix, and severity floor carried by ctx. The Logger must not be in fallback mode.
+func (lg *Logger) writeContext(ctx context.Context, e logging.Entry) {
+	if defaults, ok := EntryDefaults(ctx); ok {
+		applyEntryDefaults(&e, defaults)
+	}
+	lg.config().applyContextFields(ctx, &e)
+	applyPrefix(ctx, &e)
+	applySeverityFloor(ctx, &e)
+	lg.write(e)
+}
+
+// logContext logs payload, applying any entry defaults, prefix, and severity floor carried by ctx.
+func (lg *Logger) logContext(ctx context.Context, severity Severity, payload interface{}) {
+	if lg.logger == nil {
+		log.Print(prefixed(ctx, lg.config().fallbackPayload(payload)))
+		return
+	}
+
+	lg.writeContext(ctx, lg.entry(severity, payload))
+}
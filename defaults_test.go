@@ -0,0 +1,67 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestWithEntryDefaults(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithLabels(map[string]string{"c": "logger"}))
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	ctx = WithEntryDefaults(ctx, logging.Entry{
+		Labels:    map[string]string{"a": "first", "b": "first", "c": "first"},
+		Operation: &logpb.LogEntryOperation{Id: "op", Producer: "test"},
+	})
+	ctx = WithEntryDefaults(ctx, logging.Entry{
+		Labels: map[string]string{"b": "second"},
+	})
+
+	Infof(ctx, "hello %s", "there")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	want := map[string]interface{}{"a": "first", "b": "second", "c": "logger"}
+	if diff := pretty.Compare(want, entries[0]["logging.googleapis.com/labels"]); diff != "" {
+		t.Errorf("Unexpected labels. Diff (-want +got):\n%s", diff)
+	}
+
+	op, _ := entries[0]["logging.googleapis.com/operation"].(map[string]interface{})
+	if op["id"] != "op" || op["producer"] != "test" {
+		t.Errorf("Expected the default operation, got %v", entries[0]["logging.googleapis.com/operation"])
+	}
+}
+
+func TestApplyEntryDefaultsPrecedence(t *testing.T) {
+	e := logging.Entry{
+		Trace:  "projects/p/traces/entry",
+		Labels: map[string]string{"k": "entry"},
+	}
+	defaults := logging.Entry{
+		Trace:  "projects/p/traces/default",
+		SpanID: "0000000000000001",
+		Labels: map[string]string{"k": "default"},
+	}
+
+	applyEntryDefaults(&e, defaults)
+
+	if e.Trace != "projects/p/traces/entry" {
+		t.Errorf("Expected the entry's trace to take precedence, got %q", e.Trace)
+	}
+	if e.SpanID != "0000000000000001" {
+		t.Errorf("Expected the default span ID, got %q", e.SpanID)
+	}
+	if e.Labels["k"] != "entry" {
+		t.Errorf("Expected the entry's label to take precedence, got %q", e.Labels["k"])
+	}
+	if defaults.Labels["k"] != "default" {
+		t.Error("Expected the default labels to be unmodified")
+	}
+}
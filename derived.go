@@ -0,0 +1,92 @@
+package gaelog
+
+import (
+	"context"
+	"sync"
+)
+
+// WithDerivedLogger returns a copy of ctx whose Logger is derived from the one in ctx, for passing
+// to code deeper in a handler that should log with extra enrichment without affecting the rest of
+// the request. The package-level logging functions, when called with the returned context, use
+// the derived Logger:
+//
+//	ctx := gaelog.WithDerivedLogger(r.Context(), gaelog.WithLabels(map[string]string{"tenant": id}))
+//	gaelog.Infof(ctx, "has the tenant label")
+//	gaelog.Infof(r.Context(), "doesn't")
+//
+// The derived Logger shares the client, trace, and span of the Logger in ctx, and its entries carry
+// the same labels plus those given with WithLabels. The options are applied on top of those the
+// Logger was created with, so those that affect how entries are written, such as WithMinSeverity
+// and WithMaxPayloadSize, take effect, while those that affect how the Logger is created, such as
+// WithLogID and WithEncoder, are ignored. To also prefix its entries, combine it with WithPrefix.
+//
+// The derived Logger shares the state of the request with the Logger in ctx, so fields and errors
+// added with AddField and AddError, the user set with SetUser, and entries counted toward the cap
+// set with WithMaxEntriesPerRequest are the same whichever of the two they go through.
+//
+// The derived Logger needn't be closed; its entries, fields, and errors are flushed with those of
+// the Logger in ctx. If ctx has no Logger then it is returned as is.
+func WithDerivedLogger(ctx context.Context, options ...Option) context.Context {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey, logger.with(options))
+}
+
+// with returns a Logger that writes with lg's underlying logger but with options applied on top of
+// lg's config.
+func (lg *Logger) with(options []Option) *Logger {
+	cfg := *lg.config()
+	cfg.labels = nil
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	d := lg.derive()
+	d.labels = mergeLabels(lg.labels, cfg.labels)
+	cfg.labels = lg.config().labels
+	d.cfg = &cfg
+
+	// Share rather than own the client, and leave sampling to lg, which passes on or discards the
+	// buffered entries of both when it is closed.
+	d.client = lg.client
+	d.lazy = lg.lazy
+	d.logger = lg.logger
+	d.shared = true
+	return d
+}
+
+// requestState is the state of a request that is shared by the Logger made for it and every Logger
+// derived from that one, such as by WithDerivedLogger, Named, and Detach, so that it's the same
+// whichever of them is in a context.
+type requestState struct {
+	mu sync.Mutex
+
+	// summaryFloor is the least severity of the request's summary, as raised by EscalateSummary.
+	summaryFloor Severity
+
+	// flags are the feature flags set with SetFlags, by name.
+	flags map[string]string
+
+	// fields are the fields added with AddField, by key.
+	fields map[string]interface{}
+
+	// errs are the messages of the errors recorded with AddError.
+	errs []string
+
+	// user is the user set with SetUser.
+	user string
+
+	// entries is the number of entries counted toward the cap set with WithMaxEntriesPerRequest.
+	entries int
+}
+
+// requestState returns the Logger's shared request state, creating it if need be.
+func (lg *Logger) requestState() *requestState {
+	if s := lg.state.Load(); s != nil {
+		return s
+	}
+	lg.state.CompareAndSwap(nil, &requestState{})
+	return lg.state.Load()
+}
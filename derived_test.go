@@ -0,0 +1,106 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithDerivedLogger(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithDerivedLogger(r.Context(), WithLabels(map[string]string{"tenant": "acme"}), WithMinSeverity(SeverityInfo))
+		Debug(ctx, "dropped by the derived Logger")
+		Info(ctx, "derived")
+		Debug(r.Context(), "request")
+	}), WithLabels(map[string]string{"app": "shop"}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(rec.entries))
+	}
+
+	derived, request := rec.entries[0], rec.entries[1]
+	if derived.Labels["tenant"] != "acme" || derived.Labels["app"] != "shop" {
+		t.Errorf("Expected the derived Logger's entry to have both labels, got %v", derived.Labels)
+	}
+	if _, ok := request.Labels["tenant"]; ok || request.Labels["app"] != "shop" {
+		t.Errorf("Expected the request's Logger to be unaffected, got %v", request.Labels)
+	}
+	if derived.Trace != request.Trace || derived.Trace == "" {
+		t.Errorf("Expected both entries to share a trace, got %q and %q", derived.Trace, request.Trace)
+	}
+}
+
+func TestWithDerivedLoggerWithoutLogger(t *testing.T) {
+	ctx := context.Background()
+	if got := WithDerivedLogger(ctx, WithLabels(map[string]string{"a": "b"})); got != ctx {
+		t.Error("Expected a context without a Logger to be returned as is")
+	}
+}
+
+func TestWithDerivedLoggerSharesRequestState(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUser(r.Context(), "alice")
+
+		ctx := WithDerivedLogger(r.Context())
+		AddField(ctx, "db_queries", 3)
+		AddError(ctx, errors.New("name is required"))
+		Error(ctx, "has the parent's user")
+		SetUser(ctx, "bob")
+		Error(r.Context(), "has the derived Logger's user")
+	}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(rec.entries))
+	}
+	for i, want := range []string{"alice", "bob"} {
+		payload, _ := rec.entries[i].Payload.(map[string]interface{})
+		if got := payload["context"]; !reflect.DeepEqual(got, errorContext{User: want}) {
+			t.Errorf("Expected entry %d to have user %q, got %v", i, want, got)
+		}
+	}
+	if want := map[string]interface{}{"db_queries": 3}; !reflect.DeepEqual(rec.entries[2].Payload, want) {
+		t.Errorf("Expected fields %v, got %v", want, rec.entries[2].Payload)
+	}
+	if errs, _ := rec.entries[3].Payload.(map[string]interface{}); !reflect.DeepEqual(errs["errors"], []interface{}{"name is required"}) {
+		t.Errorf("Expected the list of errors, got %v", rec.entries[3].Payload)
+	}
+}
+
+func TestWithDerivedLoggerSharesEntryCap(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			Infof(WithDerivedLogger(r.Context()), "entry %d", i)
+		}
+	}), WithMaxEntriesPerRequest(2), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 3 {
+		t.Fatalf("Expected 2 entries and the warning, got %d entries", len(rec.entries))
+	}
+	if got := rec.entries[2].Severity; got != SeverityWarning {
+		t.Errorf("Expected the last entry to be the warning, got severity %v", got)
+	}
+}
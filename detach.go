@@ -0,0 +1,109 @@
+package gaelog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// sharedClients holds the Stackdriver Logging clients used by detached Loggers, keyed by parent.
+// They live for the life of the process, so they are never closed; entries are flushed by
+// closing the detached Loggers themselves.
+var sharedClients = struct {
+	sync.Mutex
+	m map[string]*logging.Client
+}{m: make(map[string]*logging.Client)}
+
+// sharedClient returns the shared client for parent, creating it if need be. Its errors are
+// handled as specified by the config of the Logger that first created it.
+func sharedClient(parent string, cfg *config) (*logging.Client, error) {
+	sharedClients.Lock()
+	defer sharedClients.Unlock()
+
+	if client, ok := sharedClients.m[parent]; ok {
+		return client, nil
+	}
+
+	client, err := cfg.newClient(parent)
+	if err != nil {
+		return nil, err
+	}
+	sharedClients.m[parent] = client
+	return client, nil
+}
+
+// detachedContext is a context that carries the values of its parent but is never canceled and
+// has no deadline.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// Detach returns a context for work that outlives the request, such as a goroutine started by a
+// handler that keeps running after the handler returns. The returned context carries the values
+// of ctx but is never canceled and has no deadline.
+//
+// The Logger that a wrapped handler puts in the request's context is closed when the handler
+// returns, after which entries logged with it are lost. Detach instead puts a copy of it in the
+// returned context that logs with the same trace, span, and labels but uses a Stackdriver Logging
+// client shared by all detached Loggers in the process, so it is unaffected by the request's
+// Logger being closed. Call Close on the returned context when the work is done to flush its
+// entries:
+//
+//	ctx := gaelog.Detach(r.Context())
+//	go func() {
+//		defer gaelog.Close(ctx)
+//		gaelog.Infof(ctx, "still correlated with the request")
+//	}()
+//
+// If ctx has no Logger then neither does the returned context, and logs go to the standard
+// library's log package as usual.
+func Detach(ctx context.Context) context.Context {
+	d := detachedContext{parent: ctx}
+
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		return d
+	}
+	return context.WithValue(d, ctxKey, logger.detach())
+}
+
+// detach returns a copy of the Logger that doesn't depend on its client. If a shared client
+// cannot be created then the copy is in fallback mode.
+func (lg *Logger) detach() *Logger {
+	d := lg.derive()
+
+	switch {
+	case lg.logger == nil:
+		// Nothing to detach from.
+	case lg.client == nil && lg.lazy == nil:
+		// The Logger has no client of its own, e.g. it was made with WithEncoder.
+		d.logger = lg.unsampled()
+	default:
+		cfg := lg.config()
+		if client, err := sharedClient(lg.parent, cfg); err == nil {
+			d.logger = cfg.clientLogger(client, cfg.serviceLogID(lg.service, cfg.logID))
+		}
+	}
+
+	if d.logger != nil {
+		d.track()
+	}
+	return d
+}
+
+// Close calls Close on the Logger in ctx. It is for use with contexts returned by Detach; the
+// Logger in the context of a wrapped handler is closed when the handler returns.
+func Close(ctx context.Context) error {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		return nil
+	}
+	return logger.Close()
+}
@@ -0,0 +1,62 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestDetach(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	var detached context.Context
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		detached = Detach(r.Context())
+	}), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	cancel()
+
+	if err := detached.Err(); err != nil {
+		t.Errorf("Expected the detached context not to be canceled, got %v", err)
+	}
+	if IsFallback(detached) {
+		t.Fatal("Expected the detached Logger not to be in fallback mode")
+	}
+
+	// The handler has returned and its Logger has been closed.
+	Infof(detached, "after the handler")
+	if err := Close(detached); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0]["message"] != "after the handler" {
+		t.Errorf("Unexpected message %v", entries[0]["message"])
+	}
+	want := traceID(testProjectID, "abcdef0123456789")
+	if got := entries[0]["logging.googleapis.com/trace"]; got != want {
+		t.Errorf("Expected trace %q, got %v", want, got)
+	}
+}
+
+func TestDetachUnwrapped(t *testing.T) {
+	ctx := Detach(context.Background())
+	if !IsFallback(ctx) {
+		t.Error("Expected a context without a Logger to remain without one")
+	}
+	if err := Close(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package gaelog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+const (
+	// EnvironmentAppEngine names the App Engine standard and flexible environments in
+	// WithDetectionOrder. It is detected by $GOOGLE_CLOUD_PROJECT being set.
+	EnvironmentAppEngine = "gae"
+
+	// EnvironmentCloudRun names Cloud Run in WithDetectionOrder. It is detected by $K_SERVICE
+	// being set.
+	EnvironmentCloudRun = "cloudrun"
+)
+
+// defaultDetectionOrder is the order in which environments are detected if WithDetectionOrder is
+// not given.
+var defaultDetectionOrder = []string{EnvironmentAppEngine, EnvironmentCloudRun}
+
+// WithDetectionOrder sets which environments the app is detected as running in, and in what order
+// they are tried, using the names EnvironmentAppEngine and EnvironmentCloudRun. This is for
+// deployments in which more than one environment's variables are set, or in which an environment
+// should be ignored. For example, to detect only Cloud Run even if $GOOGLE_CLOUD_PROJECT is set:
+//
+//	gaelog.WithDetectionOrder([]string{gaelog.EnvironmentCloudRun})
+//
+// Each environment but the last is skipped if it is not detected. The last is assumed, so if its
+// environment variables are not all set then the Logger falls back to the standard library's log
+// package, as it does for an unknown name. If this option is not given then App Engine is tried
+// before Cloud Run, as described in NewWithID. Other environments, such as GKE and Compute Engine,
+// are not detected, and naming them, as with any unknown name, is an error; use WithResource to log
+// as them.
+func WithDetectionOrder(order []string) Option {
+	return func(cfg *config) {
+		cfg.detectionOrder = order
+	}
+}
+
+// An environment is one in which the app can be detected as running.
+type environment struct {
+	// name is how the environment is referred to in errors.
+	name string
+
+	// present reports whether the app appears to be running in the environment.
+	present func() bool
+
+	// detect returns the service info of the environment. skipped are the names of the
+	// environments tried before it and found not to be present, for its errors.
+	detect func(cfg *config, resolveProjectID func() (string, error), skipped []string) (serviceInfo, error)
+}
+
+var environments = map[string]environment{
+	EnvironmentAppEngine: {
+		name:    "GAE",
+		present: func() bool { return os.Getenv("GOOGLE_CLOUD_PROJECT") != "" },
+		detect:  detectAppEngine,
+	},
+	EnvironmentCloudRun: {
+		name:    "Cloud Run",
+		present: func() bool { return os.Getenv("K_SERVICE") != "" },
+		detect:  detectCloudRun,
+	},
+}
+
+// detectServiceInfo is newServiceInfo with the means of finding the project ID where the
+// environment doesn't give it, as on Cloud Run, made explicit so that tests can stand in for the
+// metadata server. resolveProjectID is only called if cfg has no project ID.
+func detectServiceInfo(cfg *config, resolveProjectID func() (string, error)) (serviceInfo, error) {
+	order := cfg.detectionOrder
+	if len(order) == 0 {
+		order = defaultDetectionOrder
+	}
+
+	var skipped []string
+	for i, name := range order {
+		env, ok := environments[name]
+		if !ok {
+			return serviceInfo{}, fmt.Errorf("gaelog: unknown environment %q in detection order; only %q and %q are detected. Falling back to standard library log.", name, EnvironmentAppEngine, EnvironmentCloudRun)
+		}
+		if i < len(order)-1 && !env.present() {
+			skipped = append(skipped, env.name)
+			continue
+		}
+		return env.detect(cfg, resolveProjectID, skipped)
+	}
+	panic("unreachable")
+}
+
+// notSetError returns the error for an environment's variables not being set.
+func notSetError(skipped []string, vars string) error {
+	if len(skipped) == 0 {
+		return fmt.Errorf("gaelog: %s are expected to be set, but one or more are not. Falling back to standard library log.", vars)
+	}
+	return fmt.Errorf("gaelog: %s env vars were not set so %s are expected to be set, but one or more are not. Falling back to standard library log.", strings.Join(skipped, " or "), vars)
+}
+
+func detectAppEngine(cfg *config, resolveProjectID func() (string, error), skipped []string) (serviceInfo, error) {
+	// Get the project ID from the env var it's exposed as on App Engine.
+	gaeProjectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if gaeProjectID == "" {
+		return serviceInfo{}, notSetError(skipped, "GAE vars $GOOGLE_CLOUD_PROJECT, $GAE_SERVICE, and $GAE_VERSION")
+	}
+	if cfg.projectID != "" {
+		gaeProjectID = cfg.projectID
+	}
+
+	gaeService := os.Getenv("GAE_SERVICE")
+	gaeVersion := os.Getenv("GAE_VERSION")
+	if gaeService == "" || gaeVersion == "" {
+		return serviceInfo{}, fmt.Errorf("gaelog: $GOOGLE_CLOUD_PROJECT is set so $GAE_SERVICE and $GAE_VERSION are expected to be set, but one or both are not. Falling back to standard library log.")
+	}
+
+	// The flexible environment sets the same env vars as the standard environment, bar
+	// $GAE_ENV, and its apps' logs are of the same resource type. Flex instances are
+	// long-lived VMs rather than sandboxes, so which one handled a request is worth knowing.
+	var labels map[string]string
+	if instance := os.Getenv("GAE_INSTANCE"); instance != "" && os.Getenv("GAE_ENV") != "standard" {
+		labels = map[string]string{GAEInstanceLabel: instance}
+	}
+
+	return serviceInfo{
+		projectID: gaeProjectID,
+		resource: &monitoredres.MonitoredResource{
+			Labels: map[string]string{
+				"project_id": gaeProjectID,
+				"module_id":  gaeService,
+				"version_id": gaeVersion,
+			},
+			Type: GAEAppResourceType,
+		},
+		service: gaeService,
+		version: gaeVersion,
+		labels:  labels,
+	}, nil
+}
+
+func detectCloudRun(cfg *config, resolveProjectID func() (string, error), skipped []string) (serviceInfo, error) {
+	// Get and check the env vars expected to be set on Cloud Run.
+	crService := os.Getenv("K_SERVICE")
+	crRevision := os.Getenv("K_REVISION")
+	crConfiguration := os.Getenv("K_CONFIGURATION")
+	if crService == "" || crRevision == "" || crConfiguration == "" {
+		return serviceInfo{}, notSetError(skipped, "Cloud Run vars $K_SERVICE, $K_REVISION, and $K_CONFIGURATION")
+	}
+
+	// Finally, try the metadata service for the project ID.
+	crProjectID := cfg.projectID
+	if crProjectID == "" {
+		var err error
+		crProjectID, err = resolveProjectID()
+		if err != nil {
+			return serviceInfo{}, err
+		}
+	}
+
+	return serviceInfo{
+		projectID: crProjectID,
+		resource: &monitoredres.MonitoredResource{
+			Labels: map[string]string{
+				"project_id":         crProjectID,
+				"service_name":       crService,
+				"revision_name":      crRevision,
+				"configuration_name": crConfiguration,
+			},
+			Type: CloudRunResourceType,
+		},
+		service: crService,
+		version: crRevision,
+	}, nil
+}
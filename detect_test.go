@@ -0,0 +1,59 @@
+package gaelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithDetectionOrder(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+		"K_SERVICE":            "run-service",
+		"K_REVISION":           "run-service-00001",
+		"K_CONFIGURATION":      testConfigurationName,
+	})
+	defer unset()
+
+	resolved := func() (string, error) { return "resolved-project", nil }
+
+	info, err := detectServiceInfo(defaultConfig, resolved)
+	if err != nil || info.resource.Type != GAEAppResourceType {
+		t.Errorf("Expected App Engine to be detected by default, got %+v, %v", info.resource, err)
+	}
+
+	cfg := newConfig([]Option{WithDetectionOrder([]string{EnvironmentCloudRun, EnvironmentAppEngine})})
+	info, err = detectServiceInfo(cfg, resolved)
+	if err != nil || info.resource.Type != CloudRunResourceType || info.service != "run-service" {
+		t.Errorf("Expected Cloud Run to be detected first, got %+v, %v", info.resource, err)
+	}
+}
+
+func TestWithDetectionOrderLastAssumed(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	defer unset()
+
+	resolved := func() (string, error) { return "resolved-project", nil }
+
+	// Cloud Run isn't present, but App Engine isn't tried.
+	cfg := newConfig([]Option{WithDetectionOrder([]string{EnvironmentCloudRun})})
+	_, err := detectServiceInfo(cfg, resolved)
+	if err == nil || !strings.HasPrefix(err.Error(), "gaelog: Cloud Run vars $K_SERVICE, $K_REVISION, and $K_CONFIGURATION are expected to be set") {
+		t.Errorf("Expected Cloud Run vars to be required, got %v", err)
+	}
+
+	cfg = newConfig([]Option{WithDetectionOrder([]string{EnvironmentCloudRun, EnvironmentAppEngine})})
+	if info, err := detectServiceInfo(cfg, resolved); err != nil || info.resource.Type != GAEAppResourceType {
+		t.Errorf("Expected App Engine to be detected once Cloud Run is skipped, got %+v, %v", info.resource, err)
+	}
+
+	cfg = newConfig([]Option{WithDetectionOrder([]string{"gke"})})
+	if _, err := detectServiceInfo(cfg, resolved); err == nil || !strings.Contains(err.Error(), `unknown environment "gke"`) {
+		t.Errorf("Expected an unknown environment error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package gaelog
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// dropReason is why an entry was dropped or altered, as counted for StartDropReport.
+type dropReason int
+
+const (
+	// dropSampled is for entries dropped by WithSeveritySampling or WithErrorSampling.
+	dropSampled dropReason = iota
+	// dropTruncated is for entries truncated by WithMaxPayloadSize, which are altered rather
+	// than dropped.
+	dropTruncated
+	// dropCapped is for entries dropped by WithMaxEntriesPerRequest.
+	dropCapped
+	// dropDeduped is for entries not logged by LogOnce.
+	dropDeduped
+	// dropRateLimited is for entries dropped by WithRateLimit.
+	dropRateLimited
+	// dropBufferFull is for entries dropped by WithLazyClient because its buffer was full.
+	dropBufferFull
+
+	numDropReasons
+)
+
+// dropReasonFields are the fields of the entry logged by StartDropReport that hold the counts,
+// by reason.
+var dropReasonFields = [numDropReasons]string{"sampled", "truncated", "capped", "deduped", "rate_limited", "buffer_full"}
+
+// dropCounts are the numbers of entries dropped or altered in the process since the counts were
+// last reported, by reason.
+var dropCounts [numDropReasons]atomic.Int64
+
+// countDrop counts n entries as dropped or altered for the given reason.
+func countDrop(reason dropReason, n int) {
+	dropCounts[reason].Add(int64(n))
+}
+
+// StartDropReport logs an entry with lg every interval, until the returned function is called,
+// reporting how many entries the process dropped or altered since the last report, so that
+// silent drops can be noticed and the options responsible tuned. The entry's payload has the
+// fields "message", which summarizes the counts, and one field per reason, counting entries:
+//
+//   - "sampled": dropped by WithSeveritySampling or WithErrorSampling
+//   - "capped": dropped by WithMaxEntriesPerRequest
+//   - "deduped": not logged by LogOnce after the first time
+//   - "rate_limited": dropped by WithRateLimit; the report itself is not rate limited
+//   - "buffer_full": dropped by WithLazyClient because too many were logged before the client
+//     was ready
+//   - "truncated": truncated by WithMaxPayloadSize, and so altered rather than dropped
+//
+// The entry has SeverityWarning. Nothing is logged for an interval in which no entries were
+// dropped or altered. Counts are of all Loggers in the process, so one report suffices. The
+// returned function may be called more than once and must be called before lg is closed.
+func StartDropReport(lg *Logger, interval time.Duration) (stop func()) {
+	return every(interval, func() {
+		lg.reportDrops(interval)
+	})
+}
+
+// reportDrops logs the drop counts, if any, and resets them.
+func (lg *Logger) reportDrops(interval time.Duration) {
+	payload := make(map[string]interface{}, numDropReasons+1)
+	var dropped, total int64
+	for reason := range dropCounts {
+		n := dropCounts[reason].Swap(0)
+		payload[dropReasonFields[reason]] = n
+		total += n
+		if dropReason(reason) != dropTruncated {
+			dropped += n
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	payload["message"] = fmt.Sprintf("gaelog: dropped %d entries and truncated %d in the last %v", dropped, total-dropped, interval)
+
+	logger := lg
+	if lg.config().rateLimit != nil {
+		// Lift the limit so that the report isn't dropped by the limit it reports on.
+		logger = lg.with([]Option{withoutRateLimit})
+	}
+	logger.Log(SeverityWarning, payload)
+}
@@ -0,0 +1,43 @@
+package gaelog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportDrops(t *testing.T) {
+	for reason := range dropCounts {
+		dropCounts[reason].Store(0)
+	}
+
+	capped := &Logger{cfg: newConfig([]Option{WithMaxEntriesPerRequest(1)}), logger: nopLogger{}}
+	capped.Info("kept")
+	capped.Info("dropped")
+	capped.Info("dropped")
+
+	truncated := &Logger{cfg: newConfig([]Option{WithMaxPayloadSize(8)}), logger: nopLogger{}}
+	truncated.Info("a message too long to keep whole")
+
+	key := "drop-report-" + time.Now().String()
+	truncated.LogOnce(key, SeverityInfo, "once")
+	truncated.LogOnce(key, SeverityInfo, "twice")
+
+	rec := &recordingLogger{}
+	lg := &Logger{cfg: defaultConfig, logger: rec}
+	lg.reportDrops(time.Minute)
+	lg.reportDrops(time.Minute)
+
+	if len(rec.payloads) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(rec.payloads))
+	}
+	payload := rec.payloads[0].(map[string]interface{})
+	want := map[string]int64{"sampled": 0, "capped": 2, "deduped": 1, "truncated": 1}
+	for k, v := range want {
+		if payload[k] != v {
+			t.Errorf("Expected %s to be %d, got %v", k, v, payload[k])
+		}
+	}
+	if want := "gaelog: dropped 3 entries and truncated 1 in the last 1m0s"; payload["message"] != want {
+		t.Errorf("Expected message %q, got %q", want, payload["message"])
+	}
+}
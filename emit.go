@@ -0,0 +1,47 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// ApplyDefaults fills in the fields of e that a Logger would set, for libraries that build their
+// own entries. The request's trace, span, and resource are taken from the Logger in ctx, its labels
+// are merged into e's, and then any entry defaults carried by ctx (see WithEntryDefaults) are
+// applied. Only fields that e leaves unset are filled in, so those set by the caller take
+// precedence; likewise e's own labels take precedence over those with the same keys. The timestamp
+// is set to the current time if it is unset.
+func ApplyDefaults(ctx context.Context, e *logging.Entry) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.applyDefaults(e)
+	}
+	if defaults, ok := EntryDefaults(ctx); ok {
+		applyEntryDefaults(e, defaults)
+	}
+}
+
+// EmitEntry applies defaults to e as ApplyDefaults does and sends it with the Logger in ctx, as
+// with the package-level logging functions, so that options such as WithMinSeverity and any prefix
+// or severity floor carried by ctx apply to it as well. If ctx has no Logger, or it has fallen
+// back, then e's payload is logged using the standard library's log package.
+func EmitEntry(ctx context.Context, e logging.Entry) {
+	logger := loggerFromContext(ctx)
+	if logger == nil || logger.logger == nil {
+		log.Print(prefixed(ctx, e.Payload))
+		return
+	}
+
+	logger.applyDefaults(&e)
+	logger.writeContext(ctx, e)
+}
+
+// applyDefaults sets each unset field of e that the Logger sets on its own entries.
+func (lg *Logger) applyDefaults(e *logging.Entry) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	applyEntryDefaults(e, lg.entry(e.Severity, nil))
+}
@@ -0,0 +1,65 @@
+package gaelog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	lg := &Logger{
+		cfg:     defaultConfig,
+		trace:   traceID(testProjectID, "abcdef0123456789"),
+		spanID:  "000000000000007b",
+		sampled: true,
+		labels:  map[string]string{"app": "shop", "tier": "web"},
+	}
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	ctx = WithEntryDefaults(ctx, logging.Entry{InsertID: "default-id"})
+
+	e := logging.Entry{
+		SpanID: "00000000000001c8",
+		Labels: map[string]string{"tier": "worker"},
+	}
+	ApplyDefaults(ctx, &e)
+
+	if e.Trace != lg.trace || !e.TraceSampled {
+		t.Errorf("Expected the request's trace, got %q (sampled %v)", e.Trace, e.TraceSampled)
+	}
+	if e.SpanID != "00000000000001c8" {
+		t.Errorf("Expected the caller's span to be kept, got %q", e.SpanID)
+	}
+	if e.Labels["app"] != "shop" || e.Labels["tier"] != "worker" {
+		t.Errorf("Expected the labels to be merged with the caller's taking precedence, got %v", e.Labels)
+	}
+	if e.InsertID != "default-id" {
+		t.Errorf("Expected the context's entry defaults to be applied, got %q", e.InsertID)
+	}
+	if e.Timestamp.IsZero() {
+		t.Error("Expected the timestamp to be set")
+	}
+}
+
+func TestEmitEntry(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		EmitEntry(r.Context(), logging.Entry{Severity: logging.Debug, Payload: "dropped"})
+		EmitEntry(WithPrefix(r.Context(), "lib"), logging.Entry{Severity: logging.Info, Payload: "hello"})
+	}), WithMinSeverity(SeverityInfo), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	if e := rec.entries[0]; e.Payload != "[lib] hello" || e.Trace == "" {
+		t.Errorf("Expected a prefixed entry correlated with the request, got %+v", e)
+	}
+}
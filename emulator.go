@@ -0,0 +1,31 @@
+package gaelog
+
+import (
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// EmulatorHostEnvVar is the environment variable that, if set, gives the host and port of a Cloud
+// Logging emulator for all Loggers to use, as WithEmulatorHost does.
+const EmulatorHostEnvVar = "LOGGING_EMULATOR_HOST"
+
+// WithEmulatorHost makes the underlying Stackdriver Logging client talk to the Cloud Logging
+// emulator at the given host and port, without authentication or TLS, rather than to Cloud
+// Logging itself. This is for local integration testing. Combine it with WithProjectID to avoid
+// looking up the project ID on the metadata server. It takes precedence over EmulatorHostEnvVar,
+// and an empty host disables use of the emulator.
+func WithEmulatorHost(host string) Option {
+	return func(cfg *config) {
+		cfg.emulatorHost = host
+	}
+}
+
+// emulatorClientOptions returns the options that point a client at the emulator at host.
+func emulatorClientOptions(host string) []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(host),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+}
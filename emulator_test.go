@@ -0,0 +1,148 @@
+package gaelog
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/grpc"
+)
+
+// fakeEmulator is a Cloud Logging API server that records the entries written to it.
+type fakeEmulator struct {
+	loggingpb.UnimplementedLoggingServiceV2Server
+
+	mu      sync.Mutex
+	entries []*loggingpb.LogEntry
+
+	// writeErr, if set, is returned by every write.
+	writeErr error
+}
+
+func (f *fakeEmulator) WriteLogEntries(ctx context.Context, req *loggingpb.WriteLogEntriesRequest) (*loggingpb.WriteLogEntriesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.writeErr != nil {
+		return nil, f.writeErr
+	}
+
+	for _, e := range req.GetEntries() {
+		if e.LogName == "" {
+			e.LogName = req.GetLogName()
+		}
+		f.entries = append(f.entries, e)
+	}
+	return &loggingpb.WriteLogEntriesResponse{}, nil
+}
+
+// startFakeEmulator starts a fakeEmulator and returns it and its address.
+func startFakeEmulator(t *testing.T) (*fakeEmulator, string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	f := &fakeEmulator{}
+	s := grpc.NewServer()
+	loggingpb.RegisterLoggingServiceV2Server(s, f)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return f, lis.Addr().String()
+}
+
+func TestWithEmulatorHost(t *testing.T) {
+	f, addr := startFakeEmulator(t)
+
+	unset := setEnvVars(map[string]string{
+		"K_SERVICE":       testServiceID,
+		"K_REVISION":      testVersionID,
+		"K_CONFIGURATION": testConfigurationName,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEmulatorHost(addr), WithProjectID("emulated-project"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lg.Infof("hello %s", "emulator")
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var found bool
+	for _, e := range f.entries {
+		if e.GetTextPayload() != "hello emulator" {
+			continue
+		}
+		found = true
+
+		if want := "projects/emulated-project/logs/" + DefaultLogID; e.LogName != want {
+			t.Errorf("Expected log name %q, got %q", want, e.LogName)
+		}
+		if want := "projects/emulated-project/traces/abcdef0123456789"; e.Trace != want {
+			t.Errorf("Expected trace %q, got %q", want, e.Trace)
+		}
+		if got := e.GetResource().GetLabels()["project_id"]; got != "emulated-project" {
+			t.Errorf("Expected resource project ID %q, got %q", "emulated-project", got)
+		}
+	}
+	if !found {
+		t.Errorf("Expected the entry to be written to the emulator, got %v", f.entries)
+	}
+}
+
+func TestEmulatorHostEnvVar(t *testing.T) {
+	t.Setenv(EmulatorHostEnvVar, "localhost:8085")
+
+	if host := newConfig(nil).emulatorHost; host != "localhost:8085" {
+		t.Errorf("Expected emulator host %q, got %q", "localhost:8085", host)
+	}
+	if host := newConfig([]Option{WithEmulatorHost("")}).emulatorHost; host != "" {
+		t.Errorf("Expected the option to take precedence, got %q", host)
+	}
+}
+
+func TestWithServicePrefixedLogID(t *testing.T) {
+	f, addr := startFakeEmulator(t)
+	setGAEEnvVars(t)
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEmulatorHost(addr), WithLogID("requests"), WithServicePrefixedLogID())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lg.Infof("hello")
+	lg.Named("business_events", nil).Info("order placed")
+	lg.LogToName("deployments", SeverityNotice, "deployed v2")
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	logNames := make(map[string]string)
+	for _, e := range f.entries {
+		logNames[e.GetTextPayload()] = e.LogName
+	}
+	for msg, logID := range map[string]string{"hello": "requests", "order placed": "business_events", "deployed v2": "deployments"} {
+		if want := "projects/" + testProjectID + "/logs/" + testServiceID + "." + logID; logNames[msg] != want {
+			t.Errorf("Expected %q to be written to %q, got %q", msg, want, logNames[msg])
+		}
+	}
+}
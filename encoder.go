@@ -0,0 +1,303 @@
+package gaelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// An Encoder encodes a log entry as a single line of output. Encoders are used by Loggers
+// created with WithEncoder, which write entries to stdout rather than sending them to
+// Stackdriver Logging, leaving it to an agent or sidecar to collect them.
+//
+// The Encoders provided by this package sort the keys of JSON objects, including those of
+// payloads and of maps within them, so the output for a given entry is the same on every run.
+// This makes output stable for snapshot tests and easy to diff.
+type Encoder interface {
+	Encode(e logging.Entry) ([]byte, error)
+}
+
+var (
+	// GCPEncoder encodes entries as JSON in the structured logging format understood by the
+	// logging agents on Google Cloud. Entries written to stdout in this format on App Engine,
+	// Cloud Run, etc. are ingested by Cloud Logging with their severity, trace, labels, etc.
+	// intact. See https://cloud.google.com/logging/docs/structured-logging.
+	GCPEncoder Encoder = gcpEncoder{}
+
+	// ECSEncoder encodes entries as JSON in the Elastic Common Schema format.
+	// See https://www.elastic.co/guide/en/ecs/current/index.html.
+	ECSEncoder Encoder = ecsEncoder{}
+)
+
+// WithEncoder makes the Logger write entries to stdout using the given Encoder rather than
+// sending them to Stackdriver Logging. No Stackdriver Logging client is created. The trace and
+// resource are still determined as described in NewWithID.
+func WithEncoder(enc Encoder) Option {
+	return func(cfg *config) {
+		cfg.encoder = enc
+	}
+}
+
+// WithSplitOutput makes a Logger created with WithEncoder write entries of at least the given
+// severity to high and all others to low, rather than writing all entries to stdout. For example,
+// to follow the common convention of writing warnings and errors to stderr:
+//
+//	gaelog.WithSplitOutput(gaelog.SeverityWarning, os.Stdout, os.Stderr)
+//
+// It has no effect on Loggers that send entries to Stackdriver Logging.
+func WithSplitOutput(threshold Severity, low, high io.Writer) Option {
+	return func(cfg *config) {
+		cfg.splitOutput = &splitOutput{
+			threshold: threshold,
+			low:       low,
+			high:      high,
+		}
+	}
+}
+
+// splitOutput holds the settings given to WithSplitOutput.
+type splitOutput struct {
+	threshold Severity
+	low       io.Writer
+	high      io.Writer
+}
+
+// encoderLogger is an entryLogger that writes entries to w using an Encoder. If high is set then
+// entries at or above the threshold are written to it instead.
+type encoderLogger struct {
+	enc Encoder
+
+	mu        sync.Mutex
+	w         io.Writer
+	high      io.Writer
+	threshold Severity
+}
+
+func newEncoderLogger(cfg *config) *encoderLogger {
+	l := &encoderLogger{
+		enc: cfg.encoder,
+		w:   os.Stdout,
+	}
+	if cfg.output != nil {
+		l.w = cfg.output
+	}
+	if split := cfg.splitOutput; split != nil {
+		l.w = split.low
+		l.high = split.high
+		l.threshold = split.threshold
+	}
+	return l
+}
+
+func (l *encoderLogger) Log(e logging.Entry) {
+	if err := l.write(e); err != nil {
+		// There's nowhere better to report the error, and the entry shouldn't be lost.
+		fmt.Fprintf(os.Stderr, "gaelog: failed to write entry: %v: %v\n", err, e.Payload)
+	}
+}
+
+// LogSync is like Log but returns any error rather than reporting it. Entries are always written
+// synchronously, so ctx is unused.
+func (l *encoderLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	return l.write(e)
+}
+
+func (l *encoderLogger) write(e logging.Entry) error {
+	b, err := l.enc.Encode(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
+	w := l.w
+	if l.high != nil && e.Severity >= l.threshold {
+		w = l.high
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Flush is a no-op because entries are written as they are logged.
+func (l *encoderLogger) Flush() error {
+	return nil
+}
+
+// payloadFields returns the fields of an object payload, or nil if the payload is not a JSON object.
+func payloadFields(payload interface{}) (map[string]interface{}, error) {
+	if _, ok := payload.(string); ok {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		// Not a JSON object.
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// encodeFields adds the payload to m and marshals it. Object payloads have their fields merged
+// into m, without overriding fields already set; other payloads are set as the message. Keys are
+// sorted at every level because encoding/json sorts the keys of maps, and object payloads are
+// converted to maps by payloadFields.
+func encodeFields(m map[string]interface{}, payload interface{}) ([]byte, error) {
+	fields, err := payloadFields(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if fields == nil {
+		m["message"] = payload
+	}
+	for k, v := range fields {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+
+	return json.Marshal(m)
+}
+
+type gcpEncoder struct{}
+
+// EncodeEntry encodes e as JSON in the structured logging format understood by the logging agents
+// on Google Cloud, exactly as GCPEncoder does. It may be used independently of a Logger, such as to
+// write entries with one's own writer or to check the format in tests. If e has no timestamp then
+// the time field is omitted and Cloud Logging uses the time the entry is received.
+func EncodeEntry(e logging.Entry) ([]byte, error) {
+	return GCPEncoder.Encode(e)
+}
+
+func (gcpEncoder) Encode(e logging.Entry) ([]byte, error) {
+	m := map[string]interface{}{}
+	if !e.Timestamp.IsZero() {
+		m["time"] = e.Timestamp.Format(time.RFC3339Nano)
+	}
+	if e.Severity != logging.Default {
+		m["severity"] = strings.ToUpper(e.Severity.String())
+	}
+	if e.Trace != "" {
+		m["logging.googleapis.com/trace"] = e.Trace
+	}
+	if e.SpanID != "" {
+		m["logging.googleapis.com/spanId"] = e.SpanID
+	}
+	if e.TraceSampled {
+		m["logging.googleapis.com/trace_sampled"] = true
+	}
+	if len(e.Labels) > 0 {
+		m["logging.googleapis.com/labels"] = e.Labels
+	}
+	if e.InsertID != "" {
+		m["logging.googleapis.com/insertId"] = e.InsertID
+	}
+	if e.Operation != nil {
+		m["logging.googleapis.com/operation"] = map[string]interface{}{
+			"id":       e.Operation.Id,
+			"producer": e.Operation.Producer,
+			"first":    e.Operation.First,
+			"last":     e.Operation.Last,
+		}
+	}
+	if e.SourceLocation != nil {
+		m["logging.googleapis.com/sourceLocation"] = map[string]interface{}{
+			"file":     e.SourceLocation.File,
+			"line":     fmt.Sprint(e.SourceLocation.Line),
+			"function": e.SourceLocation.Function,
+		}
+	}
+	if e.HTTPRequest != nil {
+		m["httpRequest"] = gcpHTTPRequest(e.HTTPRequest)
+	}
+
+	return encodeFields(m, e.Payload)
+}
+
+// gcpHTTPRequest converts r to the form of the httpRequest field of structured logs.
+func gcpHTTPRequest(r *logging.HTTPRequest) map[string]interface{} {
+	m := map[string]interface{}{}
+	if r.Request != nil {
+		m["requestMethod"] = r.Request.Method
+		m["requestUrl"] = r.Request.URL.String()
+		m["userAgent"] = r.Request.UserAgent()
+		m["referer"] = r.Request.Referer()
+		m["protocol"] = r.Request.Proto
+	}
+	if r.Status != 0 {
+		m["status"] = r.Status
+	}
+	if r.RequestSize != 0 {
+		m["requestSize"] = fmt.Sprint(r.RequestSize)
+	}
+	if r.ResponseSize != 0 {
+		m["responseSize"] = fmt.Sprint(r.ResponseSize)
+	}
+	if r.Latency != 0 {
+		m["latency"] = fmt.Sprintf("%.9fs", r.Latency.Seconds())
+	}
+	if r.RemoteIP != "" {
+		m["remoteIp"] = r.RemoteIP
+	}
+	if r.LocalIP != "" {
+		m["serverIp"] = r.LocalIP
+	}
+	return m
+}
+
+type ecsEncoder struct{}
+
+// ecsVersion is the version of the Elastic Common Schema that ECSEncoder adheres to.
+const ecsVersion = "8.0.0"
+
+func (ecsEncoder) Encode(e logging.Entry) ([]byte, error) {
+	m := map[string]interface{}{
+		"@timestamp": e.Timestamp.Format(time.RFC3339Nano),
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+	}
+	if e.Severity != logging.Default {
+		m["log"] = map[string]interface{}{"level": strings.ToLower(e.Severity.String())}
+	}
+	if e.Trace != "" {
+		// ECS expects the bare trace ID, not the full resource name.
+		m["trace"] = map[string]interface{}{"id": e.Trace[strings.LastIndex(e.Trace, "/")+1:]}
+	}
+	if e.SpanID != "" {
+		m["span"] = map[string]interface{}{"id": e.SpanID}
+	}
+	if len(e.Labels) > 0 {
+		m["labels"] = e.Labels
+	}
+	if e.HTTPRequest != nil {
+		r := e.HTTPRequest
+		if r.Request != nil {
+			m["http"] = map[string]interface{}{
+				"request":  map[string]interface{}{"method": r.Request.Method},
+				"response": map[string]interface{}{"status_code": r.Status, "body": map[string]interface{}{"bytes": r.ResponseSize}},
+			}
+			m["url"] = map[string]interface{}{"full": r.Request.URL.String()}
+			m["user_agent"] = map[string]interface{}{"original": r.Request.UserAgent()}
+		}
+		if r.RemoteIP != "" {
+			m["client"] = map[string]interface{}{"ip": r.RemoteIP}
+		}
+		if r.Latency != 0 {
+			m["event"] = map[string]interface{}{"duration": r.Latency.Nanoseconds()}
+		}
+	}
+
+	return encodeFields(m, e.Payload)
+}
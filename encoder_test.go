@@ -0,0 +1,231 @@
+package gaelog
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+var testEncoderTime = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+func TestGCPEncoder(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry logging.Entry
+		want  string
+	}{
+		{
+			"text",
+			logging.Entry{
+				Timestamp: testEncoderTime,
+				Severity:  logging.Warning,
+				Payload:   "hello",
+				Trace:     "projects/p/traces/abc",
+				SpanID:    "0000000000000001",
+				Labels:    map[string]string{"a": "b"},
+			},
+			`{"logging.googleapis.com/labels":{"a":"b"},"logging.googleapis.com/spanId":"0000000000000001","logging.googleapis.com/trace":"projects/p/traces/abc","message":"hello","severity":"WARNING","time":"2020-01-02T03:04:05.000000006Z"}`,
+		},
+		{
+			"object",
+			logging.Entry{
+				Timestamp: testEncoderTime,
+				Payload:   struct{ Places []string }{[]string{"Yosemite"}},
+			},
+			`{"Places":["Yosemite"],"time":"2020-01-02T03:04:05.000000006Z"}`,
+		},
+		{
+			"object_does_not_override_special_fields",
+			logging.Entry{
+				Timestamp: testEncoderTime,
+				Severity:  logging.Info,
+				Payload:   map[string]string{"severity": "nope", "message": "hi"},
+			},
+			`{"message":"hi","severity":"INFO","time":"2020-01-02T03:04:05.000000006Z"}`,
+		},
+		{
+			"http_request",
+			logging.Entry{
+				Timestamp: testEncoderTime,
+				Payload:   "req",
+				HTTPRequest: &logging.HTTPRequest{
+					Request:      httptest.NewRequest("GET", "https://example.com/foo", nil),
+					Status:       200,
+					ResponseSize: 5,
+					Latency:      1500 * time.Millisecond,
+				},
+			},
+			`{"httpRequest":{"latency":"1.500000000s","protocol":"HTTP/1.1","referer":"","requestMethod":"GET","requestUrl":"https://example.com/foo","responseSize":"5","status":200,"userAgent":""},"message":"req","time":"2020-01-02T03:04:05.000000006Z"}`,
+		},
+		{
+			"no_timestamp",
+			logging.Entry{
+				Severity: logging.Notice,
+				Payload:  "hello",
+			},
+			`{"message":"hello","severity":"NOTICE"}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := GCPEncoder.Encode(c.entry)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("Expected\n%s\ngot\n%s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeEntry(t *testing.T) {
+	got, err := EncodeEntry(logging.Entry{Timestamp: testEncoderTime, Severity: logging.Error, Payload: "hello"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := `{"message":"hello","severity":"ERROR","time":"2020-01-02T03:04:05.000000006Z"}`
+	if string(got) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestECSEncoder(t *testing.T) {
+	got, err := ECSEncoder.Encode(logging.Entry{
+		Timestamp: testEncoderTime,
+		Severity:  logging.Error,
+		Payload:   "hello",
+		Trace:     "projects/p/traces/abc",
+		SpanID:    "0000000000000001",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := `{"@timestamp":"2020-01-02T03:04:05.000000006Z","ecs":{"version":"8.0.0"},"log":{"level":"error"},"message":"hello","span":{"id":"0000000000000001"},"trace":{"id":"abc"}}`
+	if string(got) != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestEncodersSortKeys(t *testing.T) {
+	type payload struct {
+		Zebra  string            `json:"zebra"`
+		Apple  map[string]int    `json:"apple"`
+		Mango  map[string]string `json:"mango"`
+		Banana int               `json:"banana"`
+	}
+
+	e := logging.Entry{
+		Timestamp: testEncoderTime,
+		Severity:  logging.Info,
+		Labels:    map[string]string{"z": "1", "a": "2", "m": "3"},
+		Payload: payload{
+			Zebra:  "z",
+			Apple:  map[string]int{"y": 1, "b": 2, "k": 3, "c": 4, "x": 5},
+			Mango:  map[string]string{"q": "1", "d": "2"},
+			Banana: 7,
+		},
+	}
+
+	cases := []struct {
+		name string
+		enc  Encoder
+		want string
+	}{
+		{"gcp", GCPEncoder, `{"apple":{"b":2,"c":4,"k":3,"x":5,"y":1},"banana":7,"logging.googleapis.com/labels":{"a":"2","m":"3","z":"1"},"mango":{"d":"2","q":"1"},"severity":"INFO","time":"2020-01-02T03:04:05.000000006Z","zebra":"z"}`},
+		{"ecs", ECSEncoder, `{"@timestamp":"2020-01-02T03:04:05.000000006Z","apple":{"b":2,"c":4,"k":3,"x":5,"y":1},"banana":7,"ecs":{"version":"8.0.0"},"labels":{"a":"2","m":"3","z":"1"},"log":{"level":"info"},"mango":{"d":"2","q":"1"},"zebra":"z"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Map iteration order is random, so encode repeatedly to catch any dependence on it.
+			for i := 0; i < 20; i++ {
+				b, err := c.enc.Encode(e)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if string(b) != c.want {
+					t.Fatalf("Expected\n%s\ngot\n%s", c.want, b)
+				}
+			}
+		})
+	}
+}
+
+func TestWithEncoder(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEncoder(GCPEncoder))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lg.client != nil {
+		t.Errorf("Expected no client to be created")
+	}
+
+	var buf bytes.Buffer
+	lg.logger.(*encoderLogger).w = &buf
+
+	lg.Infof("hello %s", "world")
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0]["message"] != "hello world" || entries[0]["logging.googleapis.com/trace"] != "projects/my-project/traces/abcdef0123456789" {
+		t.Errorf("Unexpected entry: %v", entries[0])
+	}
+	if entries[0]["logging.googleapis.com/spanId"] != "000000000000007b" || entries[0]["logging.googleapis.com/trace_sampled"] != true {
+		t.Errorf("Expected the entry to be correlated with the request's span, got %v", entries[0])
+	}
+}
+
+func TestWithSplitOutput(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var low, high bytes.Buffer
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEncoder(GCPEncoder), WithSplitOutput(SeverityWarning, &low, &high))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lg.Info("info")
+	lg.Warning("warning")
+	lg.Error("error")
+
+	for _, c := range []struct {
+		name string
+		buf  *bytes.Buffer
+		want []string
+	}{
+		{"low", &low, []string{"info"}},
+		{"high", &high, []string{"warning", "error"}},
+	} {
+		entries := decodeEntries(t, c.buf)
+		if len(entries) != len(c.want) {
+			t.Fatalf("Expected %d entries written to %s, got %d", len(c.want), c.name, len(entries))
+		}
+		for i, want := range c.want {
+			if got := entries[i]["message"]; got != want {
+				t.Errorf("Expected entry %d written to %s to be %q, got %v", i, c.name, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+)
+
+// AddError records err on the Logger rather than logging it right away. Errors accumulate until
+// FlushErrors is called or the Logger is closed and are then logged together as a single entry,
+// so that a handler that collects many errors, such as while validating a request, doesn't
+// clutter the request's logs with an entry for each. Nil errors are ignored. As with AddField, the
+// errors are shared with the Loggers derived from the Logger.
+func (lg *Logger) AddError(err error) {
+	if err == nil {
+		return
+	}
+
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err.Error())
+}
+
+// FlushErrors logs the errors recorded with AddError, if any, as a single entry at the given
+// severity and clears them. The entry's payload has the fields "message", which gives the number
+// of errors, and "errors", which is the list of their messages in the order they were added.
+// Errors that remain when the Logger is closed are logged at SeverityError.
+func (lg *Logger) FlushErrors(severity Severity) {
+	s := lg.requestState()
+	s.mu.Lock()
+	errs := s.errs
+	s.errs = nil
+	s.mu.Unlock()
+
+	if len(errs) == 0 {
+		return
+	}
+
+	message := "1 error"
+	if len(errs) > 1 {
+		message = fmt.Sprintf("%d errors", len(errs))
+	}
+	lg.Log(severity, map[string]interface{}{
+		"message": message,
+		"errors":  errs,
+	})
+}
+
+// AddError calls AddError on the Logger in ctx. The errors are logged when FlushErrors is called
+// or the request completes. This should be called from a handler that has been wrapped with Wrap
+// or WrapWithID. If it is called from a handler that has not been wrapped then it does nothing.
+func AddError(ctx context.Context, err error) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.AddError(err)
+	}
+}
+
+// FlushErrors calls FlushErrors on the Logger in ctx. If ctx has no Logger then it does nothing.
+func FlushErrors(ctx context.Context, severity Severity) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.FlushErrors(severity)
+	}
+}
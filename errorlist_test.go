@@ -0,0 +1,77 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAddError(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	lg.AddError(errors.New("name is required"))
+	lg.AddError(nil)
+	lg.AddError(errors.New("age must be positive"))
+	if buf.Len() != 0 {
+		t.Fatalf("Expected nothing to be logged before the errors are flushed, got %q", buf.String())
+	}
+
+	lg.FlushErrors(SeverityWarning)
+	lg.FlushErrors(SeverityWarning)
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0]["severity"] != "WARNING" {
+		t.Errorf("Expected severity WARNING, got %v", entries[0]["severity"])
+	}
+
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	if payload["message"] != "2 errors" {
+		t.Errorf("Unexpected message: %v", payload["message"])
+	}
+	want := []interface{}{"name is required", "age must be positive"}
+	if !reflect.DeepEqual(payload["errors"], want) {
+		t.Errorf("Expected errors %v, got %v", want, payload["errors"])
+	}
+}
+
+func TestAddErrorLoggedOnClose(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	lg.AddError(errors.New("name is required"))
+	lg.Close()
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0]["severity"] != "ERROR" {
+		t.Errorf("Expected severity ERROR, got %v", entries[0]["severity"])
+	}
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	if payload["message"] != "1 error" {
+		t.Errorf("Unexpected message: %v", payload["message"])
+	}
+}
+
+func TestAddErrorDerived(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	AddError(WithDerivedLogger(ctx), errors.New("name is required"))
+	lg.Named("audit", nil).AddError(errors.New("age must be positive"))
+	lg.Close()
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	want := []interface{}{"name is required", "age must be positive"}
+	if !reflect.DeepEqual(payload["errors"], want) {
+		t.Errorf("Expected errors %v, got %v", want, payload["errors"])
+	}
+}
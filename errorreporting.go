@@ -0,0 +1,146 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+const (
+	// reportedErrorEventType marks a log entry's payload as an error event for Error Reporting.
+	// See https://cloud.google.com/error-reporting/docs/formatting-error-messages.
+	reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+	// ErrorGroupLabel is the key of the label set by WithErrorGroup.
+	ErrorGroupLabel = "error_group"
+)
+
+// errorEvent is the payload of an entry reported to Error Reporting.
+type errorEvent struct {
+	Type           string              `json:"@type"`
+	Message        string              `json:"message"`
+	ServiceContext errorServiceContext `json:"serviceContext"`
+	Context        *errorContext       `json:"context,omitempty"`
+}
+
+type errorServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+type errorContext struct {
+	HTTPRequest *errorHTTPRequest `json:"httpRequest,omitempty"`
+	User        string            `json:"user,omitempty"`
+}
+
+type errorHTTPRequest struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int    `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+// errorReport holds the settings assembled from a set of ErrorReportOptions.
+type errorReport struct {
+	request *errorHTTPRequest
+	user    string
+	group   string
+}
+
+// An ErrorReportOption adds context to an error reported with ReportError.
+type ErrorReportOption func(*errorReport)
+
+// WithErrorRequest sets the HTTP request during which the error occurred, and the status code of
+// the response, if known (pass 0 if not).
+func WithErrorRequest(r *http.Request, status int) ErrorReportOption {
+	return func(rep *errorReport) {
+		rep.request = &errorHTTPRequest{
+			Method:             r.Method,
+			URL:                r.URL.String(),
+			UserAgent:          r.UserAgent(),
+			Referrer:           r.Referer(),
+			ResponseStatusCode: status,
+			RemoteIP:           remoteHost(r),
+		}
+	}
+}
+
+// WithErrorUser sets the user affected by the error. Error Reporting uses it to count affected users.
+// It overrides the user set with SetUser.
+func WithErrorUser(user string) ErrorReportOption {
+	return func(rep *errorReport) {
+		rep.user = user
+	}
+}
+
+// WithErrorGroup sets a key used to group related errors. Error Reporting groups errors by their
+// message and stack trace, so the key is prepended to the message, making errors that share a key
+// but differ in detail cluster together. The key is also set as a label with key ErrorGroupLabel.
+func WithErrorGroup(key string) ErrorReportOption {
+	return func(rep *errorReport) {
+		rep.group = key
+	}
+}
+
+// ReportError logs err at error severity in the format recognized by Error Reporting, along with
+// the current goroutine's stack trace. Nil errors are ignored.
+func (lg *Logger) ReportError(err error, options ...ErrorReportOption) {
+	if err == nil {
+		return
+	}
+
+	rep := &errorReport{}
+	for _, opt := range options {
+		opt(rep)
+	}
+
+	if rep.user == "" {
+		rep.user = lg.currentUser()
+	}
+
+	message := err.Error()
+	if rep.group != "" {
+		message = rep.group + ": " + message
+	}
+	message += "\n\n" + string(debug.Stack())
+
+	if lg.logger == nil {
+		log.Print(message)
+		return
+	}
+
+	event := errorEvent{
+		Type:    reportedErrorEventType,
+		Message: message,
+		ServiceContext: errorServiceContext{
+			Service: lg.service,
+			Version: lg.version,
+		},
+	}
+	if rep.request != nil || rep.user != "" {
+		event.Context = &errorContext{
+			HTTPRequest: rep.request,
+			User:        rep.user,
+		}
+	}
+
+	e := lg.entry(SeverityError, event)
+	if rep.group != "" {
+		e.Labels = withLabel(e.Labels, ErrorGroupLabel, rep.group)
+	}
+	lg.write(e)
+}
+
+// ReportError calls ReportError on the Logger in ctx. This should be called from a handler that has
+// been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped
+// then the error and stack trace are simply logged using the standard library's log package.
+func ReportError(ctx context.Context, err error, options ...ErrorReportOption) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	logger.ReportError(err, options...)
+}
@@ -0,0 +1,67 @@
+package gaelog
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportError(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	r := httptest.NewRequest("GET", "https://example.com/foo", nil)
+	lg.ReportError(errors.New("oh no"), WithErrorRequest(r, 500), WithErrorUser("alice"), WithErrorGroup("db"))
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	e := entries[0]
+	if e["severity"] != "ERROR" {
+		t.Errorf("Expected severity ERROR, got %v", e["severity"])
+	}
+
+	labels, _ := e["logging.googleapis.com/labels"].(map[string]interface{})
+	if labels[ErrorGroupLabel] != "db" {
+		t.Errorf("Expected label %q to be %q, got %v", ErrorGroupLabel, "db", labels)
+	}
+
+	payload := e["message"].(map[string]interface{})
+	if payload["@type"] != reportedErrorEventType {
+		t.Errorf("Expected @type %q, got %v", reportedErrorEventType, payload["@type"])
+	}
+
+	message := payload["message"].(string)
+	if !strings.HasPrefix(message, "db: oh no\n\ngoroutine ") {
+		t.Errorf("Expected message to start with the group, error, and stack trace, got %q", message)
+	}
+
+	service := payload["serviceContext"].(map[string]interface{})
+	if service["service"] != testServiceID || service["version"] != testVersionID {
+		t.Errorf("Unexpected service context: %v", service)
+	}
+
+	ctx := payload["context"].(map[string]interface{})
+	if ctx["user"] != "alice" {
+		t.Errorf("Expected user %q, got %v", "alice", ctx["user"])
+	}
+	req := ctx["httpRequest"].(map[string]interface{})
+	if req["method"] != "GET" || req["url"] != "https://example.com/foo" || req["responseStatusCode"] != float64(500) {
+		t.Errorf("Unexpected HTTP request context: %v", req)
+	}
+	// httptest.NewRequest sets the remote address to 192.0.2.1:1234.
+	if req["remoteIp"] != "192.0.2.1" {
+		t.Errorf("Expected remote IP %q without the port, got %v", "192.0.2.1", req["remoteIp"])
+	}
+}
+
+func TestReportErrorNil(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	lg.ReportError(nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged for a nil error, got %q", buf.String())
+	}
+}
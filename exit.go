@@ -0,0 +1,44 @@
+package gaelog
+
+import (
+	"fmt"
+	"os"
+)
+
+// osExit is os.Exit, replaced in tests.
+var osExit = os.Exit
+
+// WithExitOnSeverity makes the Logger exit the process with the given code after logging an entry
+// of at least the given severity, in the manner of log.Fatal, such as to stop a batch job on an
+// emergency. The Logger's entries are flushed before exiting so that the final entry, which is
+// correlated with the request like any other, isn't lost. Entries that are dropped, such as by
+// WithMinSeverity or WithMaxEntriesPerRequest, don't cause an exit. It is disabled by default.
+// Messages logged in fallback mode are unaffected.
+func WithExitOnSeverity(severity Severity, code int) Option {
+	return func(cfg *config) {
+		cfg.exitOnSeverity = &exitOnSeverity{
+			severity: severity,
+			code:     code,
+		}
+	}
+}
+
+// exitOnSeverity holds the settings given to WithExitOnSeverity.
+type exitOnSeverity struct {
+	severity Severity
+	code     int
+}
+
+// exitIfSevere flushes the Logger and exits the process if severity calls for it per
+// WithExitOnSeverity.
+func (lg *Logger) exitIfSevere(severity Severity) {
+	exit := lg.config().exitOnSeverity
+	if exit == nil || severity < exit.severity {
+		return
+	}
+
+	if err := lg.logger.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "gaelog: failed to flush before exiting: %v\n", err)
+	}
+	osExit(exit.code)
+}
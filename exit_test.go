@@ -0,0 +1,53 @@
+package gaelog
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWithExitOnSeverity(t *testing.T) {
+	var codes []int
+	osExit = func(code int) { codes = append(codes, code) }
+	defer func() { osExit = os.Exit }()
+
+	cl := &countingLogger{}
+	lg := &Logger{cfg: newConfig([]Option{WithExitOnSeverity(SeverityEmergency, 3)}), logger: cl}
+
+	lg.Alert("not yet")
+	if len(codes) != 0 {
+		t.Fatalf("Expected no exit below the severity, got %v", codes)
+	}
+
+	lg.Emergency("the end")
+	if len(codes) != 1 || codes[0] != 3 {
+		t.Errorf("Expected an exit with code 3, got %v", codes)
+	}
+	if cl.flushes != 1 {
+		t.Errorf("Expected a flush before exiting, got %d flushes", cl.flushes)
+	}
+}
+
+func TestExitOnSeverityDisabled(t *testing.T) {
+	exited := false
+	osExit = func(code int) { exited = true }
+	defer func() { osExit = os.Exit }()
+
+	lg := &Logger{cfg: defaultConfig, logger: nopLogger{}}
+	lg.Emergency("carry on")
+	if exited {
+		t.Error("Expected no exit by default")
+	}
+}
+
+func TestExitOnSeverityDropped(t *testing.T) {
+	exited := false
+	osExit = func(code int) { exited = true }
+	defer func() { osExit = os.Exit }()
+
+	lg := &Logger{cfg: newConfig([]Option{WithExitOnSeverity(SeverityEmergency, 3), WithMaxEntriesPerRequest(1)}), logger: nopLogger{}}
+	lg.Info("within the cap")
+	lg.Emergency("dropped by the cap")
+	if exited {
+		t.Error("Expected no exit for a dropped entry")
+	}
+}
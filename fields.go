@@ -0,0 +1,47 @@
+package gaelog
+
+import (
+	"context"
+)
+
+// AddField records a key/value pair on the Logger. Fields accumulate over the life of the Logger
+// and are logged together as a single structured entry when the Logger is closed, giving a tidy
+// per-request record of metrics such as query counts or bytes processed without logging an entry
+// for each. Adding a field with a key that was already added replaces the earlier value. The
+// fields are shared with the Loggers derived from the Logger with WithDerivedLogger, Named, and
+// Detach, so a field added with any of them is logged with the rest; Loggers created with Named
+// leave logging them to the Logger that created them.
+//
+// value must marshal via the encoding/json package.
+func (lg *Logger) AddField(key string, value interface{}) {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fields == nil {
+		s.fields = make(map[string]interface{})
+	}
+	s.fields[key] = value
+}
+
+// logFields logs the accumulated fields, if any, as a single entry and clears them.
+func (lg *Logger) logFields() {
+	s := lg.requestState()
+	s.mu.Lock()
+	fields := s.fields
+	s.fields = nil
+	s.mu.Unlock()
+
+	if len(fields) > 0 {
+		lg.Log(SeverityInfo, fields)
+	}
+}
+
+// AddField calls AddField on the Logger in ctx. The fields are logged when the request completes.
+// This should be called from a handler that has been wrapped with Wrap or WrapWithID. If it is
+// called from a handler that has not been wrapped then it does nothing.
+func AddField(ctx context.Context, key string, value interface{}) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.AddField(key, value)
+	}
+}
@@ -0,0 +1,76 @@
+package gaelog
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddField(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// No env vars are set, so the Logger falls back to the standard library's log package.
+	handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddField(r.Context(), "db_queries", 3)
+		AddField(r.Context(), "cache_hits", 7)
+		AddField(r.Context(), "db_queries", 4)
+
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing to be logged before the request completes, got %q", buf.String())
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 1 {
+		t.Errorf("Expected a single summary entry, got %q", got)
+	}
+	if !strings.Contains(got, "cache_hits:7") || !strings.Contains(got, "db_queries:4") {
+		t.Errorf("Expected summary to contain the accumulated fields, got %q", got)
+	}
+}
+
+func TestAddFieldNoFields(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	lg := &Logger{}
+	lg.Close()
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged, got %q", buf.String())
+	}
+}
+
+func TestAddFieldDerived(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddField(r.Context(), "db_queries", 3)
+		AddField(WithDerivedLogger(r.Context()), "cache_hits", 7)
+		loggerFromContext(r.Context()).Named("business_events", nil).AddField("orders", 1)
+	}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected a single entry, got %d", len(rec.entries))
+	}
+	want := map[string]interface{}{"db_queries": 3, "cache_hits": 7, "orders": 1}
+	if !reflect.DeepEqual(rec.entries[0].Payload, want) {
+		t.Errorf("Expected fields %v, got %v", want, rec.entries[0].Payload)
+	}
+}
@@ -0,0 +1,89 @@
+package gaelog
+
+import (
+	"os"
+	"sync"
+)
+
+// WithFileOutput makes the Logger write entries to the file at path rather than sending them to
+// Stackdriver Logging, for developing offline or without a collector. Entries are encoded as by the
+// Encoder given with WithEncoder, or as by GCPEncoder if none is given, so the file holds the same
+// structured JSON that would be written to stdout. No Stackdriver Logging client is created.
+//
+// The file is created if it doesn't exist and appended to if it does. When writing an entry would
+// make it larger than maxSize bytes, it is renamed to path with ".1" appended, replacing any file
+// of that name, and a new file is started; a maxSize of 0 disables rotation. Loggers created with
+// the same Option share the file, which stays open for the life of the process. Errors writing to
+// it are reported on stderr. WithSplitOutput takes precedence over this option.
+func WithFileOutput(path string, maxSize int64) Option {
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	return func(cfg *config) {
+		if cfg.encoder == nil {
+			cfg.encoder = GCPEncoder
+		}
+		cfg.output = f
+	}
+}
+
+// rotatingFile is an io.Writer that appends to a file, rotating it by size as described in
+// WithFileOutput. The file is opened on the first write.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// open opens the file for appending and records its size.
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rf.f = f
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate moves the current file aside and opens a new one in its place.
+func (rf *rotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+	return rf.open()
+}
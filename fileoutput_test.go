@@ -0,0 +1,55 @@
+package gaelog
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithFileOutput(t *testing.T) {
+	setGAEEnvVars(t)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	option := WithFileOutput(path, 600)
+
+	for _, msg := range []string{"first", "second", "third"} {
+		r := httptest.NewRequest("GET", "https://example.com", nil)
+		r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+		lg, err := NewWithOptions(r, option)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lg.Info(msg)
+		lg.Close()
+	}
+
+	read := func(path string) []string {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var messages []string
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &m); err != nil {
+				t.Fatalf("Expected a JSON entry, got %q: %v", line, err)
+			}
+			if m["severity"] != "INFO" || m["logging.googleapis.com/trace"] == nil {
+				t.Errorf("Expected a structured entry, got %v", m)
+			}
+			messages = append(messages, m["message"].(string))
+		}
+		return messages
+	}
+
+	// Each entry is about 250 bytes, so the file holds two at most before it's rotated.
+	if got := read(path + ".1"); strings.Join(got, ",") != "first,second" {
+		t.Errorf("Expected the rotated file to hold the first two entries, got %v", got)
+	}
+	if got := read(path); strings.Join(got, ",") != "third" {
+		t.Errorf("Expected the file to hold the last entry, got %v", got)
+	}
+}
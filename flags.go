@@ -0,0 +1,70 @@
+package gaelog
+
+import (
+	"context"
+)
+
+// FlagLabelPrefix is prepended to the names of the feature flags set with SetFlags to make the keys
+// of the labels that record their variants.
+const FlagLabelPrefix = "flag_"
+
+// SetFlags records the variants of feature flags that are active for the request, such as the arm
+// of an experiment that the user is in, for slicing the request's entries by variant in the Logs
+// Explorer. Every entry logged after it is called, including the summary of WithRequestSummary,
+// has a label for each flag whose key is the flag's name prefixed with FlagLabelPrefix and whose
+// value is the variant. Flags accumulate over calls, with later variants replacing earlier ones.
+// They are shared with the Loggers derived from the request's Logger, such as by
+// WithDerivedLogger, Named, and Detach, whichever of them they are set with.
+func (lg *Logger) SetFlags(flags map[string]string) {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = mergeLabels(s.flags, flags)
+}
+
+// Flags returns a copy of the feature flags set with SetFlags, or nil if none are set.
+func (lg *Logger) Flags() map[string]string {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.flags) == 0 {
+		return nil
+	}
+	return mergeLabels(nil, s.flags)
+}
+
+// flagLabels returns the labels that record the flags set with SetFlags, or nil if none are set.
+func (lg *Logger) flagLabels() map[string]string {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.flags) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(s.flags))
+	for name, variant := range s.flags {
+		labels[FlagLabelPrefix+name] = variant
+	}
+	return labels
+}
+
+// SetFlags calls SetFlags on the Logger in ctx. This should be called from a handler that has been
+// wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped then it
+// does nothing.
+func SetFlags(ctx context.Context, flags map[string]string) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.SetFlags(flags)
+	}
+}
+
+// FlagsFromContext returns the feature flags set with SetFlags on the Logger in ctx, or nil if ctx
+// has no Logger.
+func FlagsFromContext(ctx context.Context) map[string]string {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		return nil
+	}
+	return logger.Flags()
+}
@@ -0,0 +1,73 @@
+package gaelog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestSetFlags(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var got map[string]string
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Infof(r.Context(), "before")
+		SetFlags(r.Context(), map[string]string{"checkout": "control", "search": "v2"})
+		SetFlags(r.Context(), map[string]string{"checkout": "treatment"})
+		Infof(r.Context(), "after")
+		got = FlagsFromContext(r.Context())
+	}), WithRequestSummary(), WithLabels(map[string]string{"team": "payments"}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := map[string]string{"checkout": "treatment", "search": "v2"}
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("Unexpected flags (-got +want):\n%s", diff)
+	}
+
+	if len(rec.entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(rec.entries))
+	}
+	if _, ok := rec.entries[0].Labels[FlagLabelPrefix+"checkout"]; ok {
+		t.Errorf("Expected no flag labels before SetFlags, got %v", rec.entries[0].Labels)
+	}
+	wantLabels := map[string]string{"team": "payments", "flag_checkout": "treatment", "flag_search": "v2"}
+	for _, e := range rec.entries[1:] {
+		if diff := pretty.Compare(e.Labels, wantLabels); diff != "" {
+			t.Errorf("Unexpected labels (-got +want):\n%s", diff)
+		}
+	}
+
+	if flags := FlagsFromContext(context.Background()); flags != nil {
+		t.Errorf("Expected no flags without a Logger, got %v", flags)
+	}
+}
+
+func TestSetFlagsDerived(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetFlags(WithDerivedLogger(r.Context()), map[string]string{"checkout": "treatment"})
+		loggerFromContext(r.Context()).Named("business_events", nil).Info("order placed")
+	}), WithRequestSummary(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(rec.entries))
+	}
+	for _, e := range rec.entries {
+		if got := e.Labels[FlagLabelPrefix+"checkout"]; got != "treatment" {
+			t.Errorf("Expected the flag label on %v, got labels %v", e.Payload, e.Labels)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package gaelog
+
+import (
+	"context"
+
+	"cloud.google.com/go/logging"
+)
+
+const severityFloorKey = ctxKeyType("gaelog-severity-floor")
+
+// WithSeverityFloor returns a copy of ctx with which the package-level logging functions (Logf,
+// Log, and so on) log entries at no less than the given severity: entries below it are promoted
+// to it. This spotlights a request, for example by promoting all its entries to notice severity
+// so that they show up in a view that filters out info and debug entries. It is the inverse of
+// WithMinSeverity, which takes effect after entries are promoted.
+//
+// If ctx already carries a floor then the higher of the two is used.
+func WithSeverityFloor(ctx context.Context, severity Severity) context.Context {
+	if floor, ok := ctx.Value(severityFloorKey).(Severity); ok && floor > severity {
+		severity = floor
+	}
+	return context.WithValue(ctx, severityFloorKey, severity)
+}
+
+// applySeverityFloor promotes e to the severity floor carried by ctx, if any.
+func applySeverityFloor(ctx context.Context, e *logging.Entry) {
+	if floor, ok := ctx.Value(severityFloorKey).(Severity); ok && e.Severity < floor {
+		e.Severity = floor
+	}
+}
@@ -0,0 +1,36 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithSeverityFloor(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMinSeverity(SeverityNotice))
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	Debug(ctx, "suppressed")
+
+	ctx = WithSeverityFloor(ctx, SeverityNotice)
+	Debugf(ctx, "promoted")
+	Error(ctx, "kept")
+
+	// A lower floor doesn't lower the existing one.
+	ctx = WithSeverityFloor(ctx, SeverityInfo)
+	Info(ctx, "still promoted")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	for i, want := range []struct{ message, severity string }{
+		{"promoted", "NOTICE"},
+		{"kept", "ERROR"},
+		{"still promoted", "NOTICE"},
+	} {
+		if entries[i]["message"] != want.message || entries[i]["severity"] != want.severity {
+			t.Errorf("Expected entry %d to be %q at %s, got %v", i, want.message, want.severity, entries[i])
+		}
+	}
+}
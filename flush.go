@@ -0,0 +1,105 @@
+package gaelog
+
+import (
+	"context"
+	"sync"
+)
+
+// activeLoggers holds the Loggers that have been created but not yet closed, for FlushAll. Loggers
+// that share another's underlying logger, such as those made by a Handler for its requests, are
+// not held since flushing the other covers them.
+var activeLoggers = struct {
+	sync.Mutex
+	m map[*Logger]struct{}
+}{m: make(map[*Logger]struct{})}
+
+// track adds the Logger to activeLoggers. The Logger must not be in fallback mode.
+func (lg *Logger) track() {
+	activeLoggers.Lock()
+	defer activeLoggers.Unlock()
+	activeLoggers.m[lg] = struct{}{}
+}
+
+// untrack removes the Logger from activeLoggers.
+func (lg *Logger) untrack() {
+	activeLoggers.Lock()
+	defer activeLoggers.Unlock()
+	delete(activeLoggers.m, lg)
+}
+
+// FlushAll flushes every Logger that has been created but not yet closed, including those of
+// wrapped handlers' in-flight requests, detached Loggers, and Handlers, blocking until their
+// buffered entries have been sent or ctx is done. It is for clean shutdown, such as when Cloud Run
+// scales in and sends SIGTERM, after which an instance has a few seconds before it is stopped:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := gaelog.FlushAll(ctx); err != nil {
+//		log.Printf("Failed to flush logs: %v", err)
+//	}
+//
+// Loggers are flushed concurrently. If ctx is done first then its error is returned; otherwise the
+// first error returned by a flush is. Errors are also kept to be returned by each Logger's Sync, as
+// flushing takes them from the underlying client. Entries logged after FlushAll is called may not
+// be flushed by it, so it is best called once the server has stopped accepting requests.
+func FlushAll(ctx context.Context) error {
+	activeLoggers.Lock()
+	loggers := make([]*Logger, 0, len(activeLoggers.m))
+	for lg := range activeLoggers.m {
+		loggers = append(loggers, lg)
+	}
+	activeLoggers.Unlock()
+
+	errs := make(chan error, len(loggers))
+	for _, lg := range loggers {
+		go func(lg *Logger) {
+			err := lg.logger.Flush()
+			if err != nil {
+				lg.writeError(err)
+			}
+			errs <- err
+		}(lg)
+	}
+
+	var firstErr error
+	for range loggers {
+		select {
+		case err := <-errs:
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return firstErr
+}
+
+// flushOnDone flushes the Logger if ctx is done before the returned function is called, such as
+// when a client disconnects or a request times out while its handler is still running, so that
+// entries logged so far are sent promptly rather than when the handler gets around to returning.
+// The Logger is flushed rather than closed because the handler may still log. The returned
+// function must be called before the Logger is closed; it waits for any flush to finish.
+func (lg *Logger) flushOnDone(ctx context.Context) (stop func()) {
+	if lg.logger == nil || ctx.Done() == nil {
+		return func() {}
+	}
+
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			if err := lg.logger.Flush(); err != nil {
+				lg.writeError(err)
+			}
+		case <-stopped:
+		}
+	}()
+
+	return func() {
+		close(stopped)
+		<-exited
+	}
+}
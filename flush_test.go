@@ -0,0 +1,174 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stuckLogger is an entryLogger whose flushes don't complete until release is closed.
+type stuckLogger struct {
+	nopLogger
+	release chan struct{}
+}
+
+func (l stuckLogger) Flush() error {
+	<-l.release
+	return nil
+}
+
+// failingLogger is an entryLogger whose flushes fail.
+type failingLogger struct {
+	nopLogger
+}
+
+func (failingLogger) Flush() error {
+	return errors.New("flush failed")
+}
+
+func TestFlushAll(t *testing.T) {
+	cl := &countingLogger{}
+	lg := &Logger{cfg: defaultConfig, logger: cl}
+	lg.track()
+
+	if err := FlushAll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cl.flushes != 1 {
+		t.Errorf("Expected 1 flush, got %d", cl.flushes)
+	}
+
+	lg.Close()
+	flushes := cl.flushes
+	if err := FlushAll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cl.flushes != flushes {
+		t.Errorf("Expected a closed Logger not to be flushed")
+	}
+}
+
+// failOnceLogger is an entryLogger whose first flush fails, as a Stackdriver Logging client's flush
+// returns the error writing its entries only once.
+type failOnceLogger struct {
+	nopLogger
+	failed bool
+}
+
+func (l *failOnceLogger) Flush() error {
+	if l.failed {
+		return nil
+	}
+	l.failed = true
+	return errors.New("flush failed")
+}
+
+func TestFlushAllError(t *testing.T) {
+	lg := &Logger{cfg: defaultConfig, logger: &failOnceLogger{}}
+	lg.track()
+	defer lg.untrack()
+
+	if err := FlushAll(context.Background()); err == nil || err.Error() != "flush failed" {
+		t.Errorf("Expected the flush error, got %v", err)
+	}
+	if err := lg.Sync(); err == nil || err.Error() != "flush failed" {
+		t.Errorf("Expected Sync to return the error from FlushAll, got %v", err)
+	}
+}
+
+func TestFlushAllContextDone(t *testing.T) {
+	stuck := stuckLogger{release: make(chan struct{})}
+	defer close(stuck.release)
+
+	lg := &Logger{cfg: defaultConfig, logger: stuck}
+	lg.track()
+	defer lg.untrack()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := FlushAll(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestFlushAllInFlight(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var during, after int
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = len(activeLoggers.m)
+	}), WithSink(nopLogger{}))
+
+	before := len(activeLoggers.m)
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	after = len(activeLoggers.m)
+
+	if during != before+1 {
+		t.Errorf("Expected the request's Logger to be tracked while in flight")
+	}
+	if after != before {
+		t.Errorf("Expected the request's Logger to be untracked once closed")
+	}
+}
+
+func TestFlushOnDone(t *testing.T) {
+	nl := notifyingLogger{flushed: make(chan struct{}, 1)}
+	lg := &Logger{cfg: defaultConfig, logger: nl}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := lg.flushOnDone(ctx)
+	cancel()
+	select {
+	case <-nl.flushed:
+	case <-time.After(time.Second):
+		t.Error("Expected a flush when the context is done")
+	}
+	stop()
+
+	cl := &countingLogger{}
+	lg = &Logger{cfg: defaultConfig, logger: cl}
+	ctx, cancel = context.WithCancel(context.Background())
+	stop = lg.flushOnDone(ctx)
+	stop()
+	cancel()
+	if cl.flushes != 0 {
+		t.Errorf("Expected no flush once stopped, got %d flushes", cl.flushes)
+	}
+}
+
+// notifyingLogger is an entryLogger that sends on flushed when flushed.
+type notifyingLogger struct {
+	nopLogger
+	flushed chan struct{}
+}
+
+func (l notifyingLogger) Flush() error {
+	l.flushed <- struct{}{}
+	return nil
+}
+
+func TestWrapFlushesOnCancel(t *testing.T) {
+	setGAEEnvVars(t)
+
+	nl := notifyingLogger{flushed: make(chan struct{}, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stand in for the client disconnecting while the handler is still running.
+		cancel()
+		select {
+		case <-nl.flushed:
+		case <-time.After(time.Second):
+			t.Error("Expected the Logger to be flushed once the request's context is done")
+		}
+		Info(r.Context(), "still logging")
+	}), WithSink(nl))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil).WithContext(ctx)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -2,12 +2,13 @@
 package gaelog
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/compute/metadata"
@@ -29,92 +30,154 @@ const (
 	// This matches the type that Cloud Run itself assigns to request logs.
 	CloudRunResourceType = "cloud_run_revision"
 
+	// GAEInstanceLabel is the key of the label set on entries logged on the App Engine flexible
+	// environment, whose value is the ID of the instance that handled the request. App Engine's
+	// own request logs carry the same label.
+	GAEInstanceLabel = "appengine.googleapis.com/instance_name"
+
+	// ProjectNumberLabel is the key of the label set by WithProjectNumberLabel.
+	ProjectNumberLabel = "project_number"
+
+	// InstanceIDLabel is the key of the label set by WithInstanceIDLabel. Cloud Run's own request
+	// logs carry the same label.
+	InstanceIDLabel = "instanceId"
+
 	traceContextHeaderName = "X-Cloud-Trace-Context"
 )
 
 var (
-	metadataOnce sync.Once
-
-	metadataProjectID    string
-	metadataProjectIDErr error
+	metadataProjectID        metadataLookup
+	metadataNumericProjectID metadataLookup
+	metadataInstanceID       metadataLookup
 )
 
 // projectIDFromMetadataService fetches the project ID from the metadata server,
 // memoizing the result for use on all but the first call.
-func projectIDFromMetadataService() (string, error) {
-	metadataOnce.Do(func() {
-		metadataProjectID, metadataProjectIDErr = metadata.ProjectID()
-	})
-	return metadataProjectID, metadataProjectIDErr
+func projectIDFromMetadataService(cfg *config) (string, error) {
+	return metadataProjectID.get(cfg, (*metadata.Client).ProjectID)
+}
+
+// NumericProjectID returns the numeric project ID (aka project number) of the current project,
+// which some monitored resource types and log filters use rather than the project ID. It is
+// fetched from the metadata server and memoized for use on all but the first call.
+func NumericProjectID() (string, error) {
+	return numericProjectID(defaultConfig)
+}
+
+func numericProjectID(cfg *config) (string, error) {
+	return metadataNumericProjectID.get(cfg, (*metadata.Client).NumericProjectID)
+}
+
+// instanceID fetches the ID of the instance the app is running on from the metadata server,
+// memoizing the result for use on all but the first call.
+func instanceID(cfg *config) (string, error) {
+	return metadataInstanceID.get(cfg, (*metadata.Client).InstanceID)
 }
 
 func traceID(projectID, trace string) string {
 	return fmt.Sprintf("projects/%s/traces/%s", projectID, trace)
 }
 
+// parseTraceContext splits the value of the X-Cloud-Trace-Context header, which has the form
+// "TRACE_ID/SPAN_ID;o=TRACE_TRUE", into its parts. The span ID and options are optional.
+//
+// Some proxies append to the header rather than replace it, yielding multiple comma-separated
+// values. By convention the first value is used.
+func parseTraceContext(header string) (trace, span string, sampled bool) {
+	header, _, _ = strings.Cut(header, ",")
+	header = strings.TrimSpace(header)
+
+	trace, rest, _ := strings.Cut(header, "/")
+	span, opts, _ := strings.Cut(rest, ";")
+	return trace, span, opts == "o=1"
+}
+
 type serviceInfo struct {
 	projectID string
 	resource  *monitoredres.MonitoredResource
-}
 
-func newServiceInfo() (serviceInfo, error) {
-	// First try getting the project ID from the env var it's exposed as on App Engine.
-	gaeProjectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	if gaeProjectID != "" {
-		gaeService := os.Getenv("GAE_SERVICE")
-		gaeVersion := os.Getenv("GAE_VERSION")
-		if gaeService == "" || gaeVersion == "" {
-			return serviceInfo{}, fmt.Errorf("gaelog: $GOOGLE_CLOUD_PROJECT is set so $GAE_SERVICE and $GAE_VERSION are expected to be set, but one or both are not. Falling back to standard library log.")
-		}
+	// service and version identify the running code, for example in Error Reporting.
+	service string
+	version string
 
-		return serviceInfo{
-			projectID: gaeProjectID,
-			resource: &monitoredres.MonitoredResource{
-				Labels: map[string]string{
-					"project_id": gaeProjectID,
-					"module_id":  gaeService,
-					"version_id": gaeVersion,
-				},
-				Type: GAEAppResourceType,
-			},
-		}, nil
-	}
-
-	// Get and check the env vars expected to be set on Cloud Run.
-	crService := os.Getenv("K_SERVICE")
-	crRevision := os.Getenv("K_REVISION")
-	crConfiguration := os.Getenv("K_CONFIGURATION")
-	if crService == "" || crRevision == "" || crConfiguration == "" {
-		return serviceInfo{}, fmt.Errorf("gaelog: GAE env vars were not set so Cloud Run vars $K_SERVICE, $K_REVISION, and $K_CONFIGURATION are expected to be set, but one or more are not. Falling back to standard library log.")
-	}
-
-	// Finally, try the metadata service for the project ID.
-	crProjectID, err := projectIDFromMetadataService()
-	if err != nil {
-		return serviceInfo{}, err
-	}
+	// labels are set on every entry in addition to those given as options.
+	labels map[string]string
+}
 
-	return serviceInfo{
-		projectID: crProjectID,
-		resource: &monitoredres.MonitoredResource{
-			Labels: map[string]string{
-				"project_id":         crProjectID,
-				"service_name":       crService,
-				"revision_name":      crRevision,
-				"configuration_name": crConfiguration,
-			},
-			Type: CloudRunResourceType,
-		},
-	}, nil
+// newServiceInfo detects the environment the app is running in. If cfg has a project ID then it
+// is used rather than the project ID of the environment.
+func newServiceInfo(cfg *config) (serviceInfo, error) {
+	return detectServiceInfo(cfg, func() (string, error) {
+		return projectIDFromMetadataService(cfg)
+	})
 }
 
 // A Logger logs messages to Stackdriver Logging (though in certain cases it may fall back to the
 // standard library's "log" package; see New). Logs will be correlated with requests in Stackdriver.
 type Logger struct {
+	cfg    *config
+	parent string
 	client *logging.Client
-	logger *logging.Logger
+	logger entryLogger
+	lazy   *lazyLogger
+
+	// sampler is set if the Logger was created with WithErrorSampling and its trace isn't sampled.
+	sampler *samplingLogger
+
+	// shared is whether client and lazy belong to the Handler that created the Logger rather than
+	// to the Logger itself.
+	shared bool
+
 	monRes *monitoredres.MonitoredResource
 	trace  string
+	spanID string
+	labels map[string]string
+
+	// sampled is whether the request's trace is sampled, per its trace context.
+	sampled bool
+
+	// correlationID is only set if the Logger was created with WithCorrelationID.
+	correlationID string
+
+	service string
+	version string
+
+	// span is only set if a SpanWriter was given, in which case it is written when the Logger is closed.
+	span       *Span
+	spanWriter SpanWriter
+
+	// req is only set if the Logger was created with WithRouteLabel, which examines it to find
+	// the route that matched. routeLabels is the Logger's labels plus the route once known.
+	req *http.Request
+
+	mu          sync.Mutex
+	routeLabels map[string]string
+
+	// state is shared with the Loggers derived from this one; see requestState.
+	state atomic.Pointer[requestState]
+
+	// named are the Loggers created by LogToName, by log ID. namedViews are those and the
+	// Loggers created by Named that are still open, which are closed when the Logger is.
+	// namedBy is the Logger that created this one with Named or LogToName, if any.
+	named      map[string]*Logger
+	namedViews map[*Logger]struct{}
+	namedBy    *Logger
+
+	// tail is only set if the Logger was created with WithRequestTail.
+	tail *entryTail
+
+	// writeErrs are the errors writing entries since Sync was last called.
+	writeErrs []error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// entryLogger is the subset of the methods of *logging.Logger used by Logger.
+type entryLogger interface {
+	Log(e logging.Entry)
+	LogSync(ctx context.Context, e logging.Entry) error
+	Flush() error
 }
 
 // NewWithID creates a new Logger. The Logger is initialized using environment variables that are
@@ -124,6 +187,9 @@ type Logger struct {
 //   • GAE_SERVICE
 //   • GAE_VERSION
 //
+// These are set on both the standard and flexible environments. On the flexible environment
+// entries are also labeled with the instance ID given by GAE_INSTANCE; see GAEInstanceLabel.
+//
 // If they are not present then it is initialized using environment variables present on Cloud Run:
 //
 //   • K_SERVICE
@@ -131,6 +197,8 @@ type Logger struct {
 //   • K_CONFIGURATION
 //   • Project ID is fetched from the metadata server, not an env var
 //
+// WithDetectionOrder changes which of these environments are tried, and in what order.
+//
 // The given log ID will be passed through to the underlying Stackdriver Logging logger.
 //
 // Additionally, options (of type LoggerOption, from cloud.google.com/go/logging) will be passed
@@ -144,61 +212,382 @@ type Logger struct {
 // in which the error will be non-nil:
 //
 //   1. Any of the aforementioned environment variables are not set.
-//   2. The given http.Request does not have the X-Cloud-Trace-Context header.
+//   2. The given http.Request does not have the X-Cloud-Trace-Context header, or, if other
+//      formats are accepted with WithTracePropagators, has no trace context in any of them.
+//      WithOptionalTrace prevents this case.
 //   3. Initialization of the underlying Stackdriver Logging client produced an error.
 func NewWithID(r *http.Request, logID string, options ...logging.LoggerOption) (*Logger, error) {
-	info, err := newServiceInfo()
+	return NewWithOptions(r, WithLogID(logID), WithLoggerOptions(options...))
+}
+
+// New is identical to NewWithID with the exception that it uses the default log ID, which is
+// DefaultLogID unless another is set with Configure.
+func New(r *http.Request, options ...logging.LoggerOption) (*Logger, error) {
+	return NewWithOptions(r, WithLoggerOptions(options...))
+}
+
+// NewWithOptions is like NewWithID but is configured using Options, which allow for behavior
+// beyond setting the log ID and passing through options to the underlying Stackdriver Logging
+// logger. With no options it is identical to New.
+func NewWithOptions(r *http.Request, options ...Option) (*Logger, error) {
+	return newLogger(r, newConfig(options))
+}
+
+func newLogger(r *http.Request, cfg *config) (*Logger, error) {
+	lg, err := newRequestLogger(r, cfg)
 	if err != nil {
-		return &Logger{}, err
+		return lg, err
 	}
 
-	traceContext := r.Header.Get(traceContextHeaderName)
-	if traceContext == "" {
-		return &Logger{}, fmt.Errorf("gaelog: %s header is not set, falling back to standard library log", traceContextHeaderName)
+	if err := lg.open(); err != nil {
+		return &Logger{cfg: cfg}, err
 	}
+	// Sample before tracking so that FlushAll never sees the Logger without its sampler.
+	lg.sample()
+	lg.track()
+	return lg, nil
+}
 
-	client, err := logging.NewClient(r.Context(), fmt.Sprintf("projects/%s", info.projectID))
+// open sets up the Logger's underlying logger, creating a Stackdriver Logging client for
+// lg.parent if its config calls for one.
+func (lg *Logger) open() error {
+	cfg := lg.config()
+	switch {
+	case cfg.sink != nil:
+		lg.logger = sinkLogger{cfg.sink}
+	case cfg.encoder != nil:
+		lg.logger = newEncoderLogger(cfg)
+	case cfg.lazyClient:
+		lg.lazy = newLazyLogger(lg.parent, cfg.serviceLogID(lg.service, cfg.logID), cfg)
+		lg.logger = lg.lazy
+	default:
+		client, err := cfg.newClient(lg.parent)
+		if err != nil {
+			return err
+		}
+		if err := cfg.checkHealth(client); err != nil {
+			return err
+		}
+		lg.client = client
+		lg.logger = cfg.clientLogger(client, cfg.serviceLogID(lg.service, cfg.logID))
+	}
+	return nil
+}
+
+// newRequestLogger returns a Logger whose entries are correlated with r but that has no underlying
+// logger, so it is in fallback mode until one is set. If the Logger cannot be correlated with r
+// then the error says why.
+func newRequestLogger(r *http.Request, cfg *config) (*Logger, error) {
+	info, err := newServiceInfo(cfg)
 	if err != nil {
-		return &Logger{}, err
+		return &Logger{cfg: cfg}, err
+	}
+
+	trace, parentSpan, sampled := cfg.extractTrace(r)
+	if trace == "" && !cfg.optionalTrace {
+		return &Logger{cfg: cfg}, cfg.noTraceError()
+	}
+
+	lg := &Logger{
+		cfg:     cfg,
+		monRes:  info.resource,
+		sampled: sampled,
+		labels:  cfg.labels,
+
+		service: info.service,
+		version: info.version,
 	}
 
-	return &Logger{
-		client: client,
-		logger: client.Logger(logID, options...),
-		monRes: info.resource,
-		trace:  traceID(info.projectID, strings.Split(traceContext, "/")[0]),
-	}, nil
+	if cfg.resource != nil {
+		lg.monRes = cfg.resource
+	}
+
+	if len(info.labels) > 0 {
+		lg.labels = mergeLabels(info.labels, cfg.labels)
+	}
+
+	if cfg.route != nil {
+		lg.req = r
+	}
+
+	lg.parent = cfg.parent(info)
+
+	if cfg.projectNumberLabel {
+		// The project number is a nicety, so failing to fetch it shouldn't cause a fall back.
+		if num, err := numericProjectID(cfg); err == nil {
+			lg.labels = withLabel(lg.labels, ProjectNumberLabel, num)
+		}
+	}
+
+	if cfg.instanceIDLabel {
+		if id, err := instanceID(cfg); err == nil {
+			lg.labels = withLabel(lg.labels, InstanceIDLabel, id)
+		}
+	}
+
+	if cfg.requestTail > 0 {
+		lg.tail = newEntryTail(cfg.requestTail)
+	}
+
+	if id := cfg.correlationID(r); id != "" {
+		lg.correlationID = id
+		lg.labels = withLabel(lg.labels, CorrelationIDLabel, id)
+	}
+
+	if trace == "" {
+		// The request isn't traced, as allowed by WithOptionalTrace, so there is nothing to
+		// correlate its entries with.
+		return lg, nil
+	}
+	lg.trace = traceID(info.projectID, trace)
+
+	if cfg.spans {
+		lg.spanID = cfg.newSpanID()
+		if cfg.spanWriter != nil {
+			lg.spanWriter = cfg.spanWriter
+			lg.span = &Span{
+				ProjectID:    info.projectID,
+				TraceID:      trace,
+				SpanID:       lg.spanID,
+				ParentSpanID: parentSpan,
+				Name:         r.URL.Path,
+				Start:        time.Now(),
+			}
+		}
+	} else {
+		// Attribute entries to the request's own span so that they're correlated with it even
+		// when written to stdout, where there's no API client to do any correlation.
+		lg.spanID = parentSpan
+	}
+
+	return lg, nil
 }
 
-// New is identical to NewWithID with the exception that it uses the default log ID.
-func New(r *http.Request, options ...logging.LoggerOption) (*Logger, error) {
-	return NewWithID(r, DefaultLogID, options...)
+// NewOrFallback is like NewWithOptions but, rather than returning an error, it logs the error at
+// notice severity using the fallback Logger. It suits simple apps that would handle the error that
+// way anyway, since the returned Logger is valid in either case.
+func NewOrFallback(r *http.Request, options ...Option) *Logger {
+	lg, err := NewWithOptions(r, options...)
+	if err != nil {
+		lg.Noticef("%v", err)
+	}
+	return lg
 }
 
 // Close closes the Logger, ensuring all logs are flushed and closing the underlying
-// Stackdriver Logging client.
+// Stackdriver Logging client. Any fields added with AddField and errors added with AddError are
+// logged first, and any Loggers created with Named and LogToName are closed. If the Logger was
+// created with WithSpanWriter then the Logger's span is written too. Calling Close more than
+// once is safe; calls after the first do nothing and return the first call's error.
 func (lg *Logger) Close() error {
-	if lg.client != nil {
-		return lg.client.Close()
+	lg.closeOnce.Do(func() {
+		lg.closeErr = lg.close()
+	})
+	return lg.closeErr
+}
+
+func (lg *Logger) close() error {
+	defer lg.untrack()
+	if lg.namedBy != nil {
+		defer lg.namedBy.forgetNamed(lg)
 	}
 
-	return nil
+	if lg.namedBy == nil {
+		// Fields and errors are logged to the request's own log rather than to a named one.
+		lg.logFields()
+		lg.FlushErrors(SeverityError)
+	}
+	namedErr := lg.closeNamed()
+
+	var spanErr error
+	if lg.span != nil {
+		lg.span.End = time.Now()
+		spanErr = lg.spanWriter.WriteSpan(context.Background(), *lg.span)
+		lg.span = nil
+	}
+
+	if lg.sampler != nil {
+		lg.sampler.discard()
+	}
+
+	var err error
+	switch {
+	case lg.shared:
+		// The Handler that created the Logger owns its client and flushes it.
+	case lg.client != nil:
+		err = lg.client.Close()
+	case lg.lazy != nil:
+		err = lg.lazy.Close()
+	case lg.logger != nil:
+		// The Logger doesn't own its client, as is the case for detached Loggers, so flush rather
+		// than close it.
+		err = lg.logger.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if namedErr != nil {
+		return namedErr
+	}
+
+	return spanErr
+}
+
+// derive returns a Logger whose entries are correlated with the same request as lg's and carry the
+// same labels. It has no underlying logger, so it is in fallback mode until one is set.
+func (lg *Logger) derive() *Logger {
+	d := &Logger{
+		cfg:     lg.cfg,
+		parent:  lg.parent,
+		monRes:  lg.monRes,
+		trace:   lg.trace,
+		spanID:  lg.spanID,
+		sampled: lg.sampled,
+		labels:  lg.labels,
+
+		correlationID: lg.correlationID,
+		tail:          lg.tail,
+
+		service: lg.service,
+		version: lg.version,
+
+		req: lg.req,
+	}
+	d.state.Store(lg.requestState())
+	return d
+}
+
+// entry makes a log entry with the given severity and payload that is correlated with the request.
+func (lg *Logger) entry(severity Severity, payload interface{}) logging.Entry {
+	e := logging.Entry{
+		Timestamp:    time.Now(),
+		Severity:     severity,
+		Payload:      payload,
+		Labels:       lg.entryLabels(),
+		Trace:        lg.trace,
+		SpanID:       lg.spanID,
+		TraceSampled: lg.sampled,
+		Resource:     lg.monRes,
+	}
+
+	if flags := lg.flagLabels(); flags != nil {
+		e.Labels = mergeLabels(e.Labels, flags)
+	}
+
+	if lg.config().goroutineLabel {
+		if id := goroutineID(); id != "" {
+			e.Labels = withLabel(e.Labels, GoroutineLabel, id)
+		}
+	}
+
+	return e
+}
+
+// IsFallback reports whether the Logger has fallen back to the standard library's log package
+// (see NewWithID). This is not always apparent from the error returned when the Logger was created;
+// for example, a Logger is valid but in fallback mode when created with Wrap.
+func (lg *Logger) IsFallback() bool {
+	return lg.logger == nil
+}
+
+// config returns the Logger's config. Loggers made without one, such as the zero Logger used when
+// a handler isn't wrapped, use the default config.
+func (lg *Logger) config() *config {
+	if lg.cfg == nil {
+		return defaultConfig
+	}
+	return lg.cfg
+}
+
+// write sends the entry to Stackdriver Logging. The Logger must not be in fallback mode.
+func (lg *Logger) write(e logging.Entry) {
+	if e.Severity == SeverityDefault {
+		e.Severity = lg.config().defaultSeverity
+	}
+
+	if e.Severity < lg.config().minSeverity {
+		return
+	}
+	if !lg.keepSeverity(e.Severity) {
+		countDrop(dropSampled, 1)
+		return
+	}
+	if !lg.admit() {
+		countDrop(dropCapped, 1)
+		return
+	}
+	if !lg.allowRate() {
+		countDrop(dropRateLimited, 1)
+		return
+	}
+
+	// Exit only once the entry is written, not if it was dropped.
+	defer lg.exitIfSevere(e.Severity)
+
+	if b, ok := e.Payload.([]byte); ok {
+		e.Payload = lg.config().bytesPayload(b)
+	}
+
+	if lg.config().checkPayloads {
+		e = checkPayload(e)
+	}
+
+	liftOperation(&e)
+
+	if cfg := lg.config(); cfg.synthesizeMessage {
+		e.Payload = withMessage(e.Payload, cfg.messageField)
+	}
+
+	if e.Severity >= SeverityError {
+		if user := lg.currentUser(); user != "" {
+			e.Payload = withUser(e.Payload, user)
+		}
+	}
+
+	if limit := lg.config().maxPayloadSize; limit > 0 {
+		if payload, truncated := truncatePayload(e.Payload, limit); truncated {
+			e.Payload = payload
+			e.Labels = withLabel(e.Labels, TruncatedLabel, "true")
+			countDrop(dropTruncated, 1)
+		}
+	}
+
+	if d := lg.config().timestampPrecision; d > 0 {
+		e.Timestamp = e.Timestamp.Truncate(d)
+	}
+
+	if lg.tail != nil {
+		lg.tail.add(e)
+	}
+
+	if lg.config().writesSync(e.Severity) {
+		lg.writeSync(e)
+		return
+	}
+
+	lg.logger.Log(e)
+	lg.flushAfterWrite()
+}
+
+// sprintf is like fmt.Sprintf but returns format as is, without the cost of formatting it, if
+// there is nothing to format.
+func sprintf(format string, v ...interface{}) string {
+	if len(v) == 0 && strings.IndexByte(format, '%') < 0 {
+		return format
+	}
+	return fmt.Sprintf(format, v...)
 }
 
 // Logf logs with the given severity. Remaining arguments are handled in the manner of fmt.Printf.
-func (lg *Logger) Logf(severity logging.Severity, format string, v ...interface{}) {
+func (lg *Logger) Logf(severity Severity, format string, v ...interface{}) {
 	if lg.logger == nil {
 		log.Printf(format, v...)
 		return
 	}
 
-	lg.logger.Log(logging.Entry{
-		Timestamp: time.Now(),
-		Severity:  severity,
-		Payload:   fmt.Sprintf(format, v...),
-		Trace:     lg.trace,
-		Resource:  lg.monRes,
-	})
+	msg := sprintf(format, v...)
+	lg.write(lg.entry(severity, msg))
+	lg.checkFormat(format, v, msg)
 }
 
 // Debugf calls Logf with debug severity.
@@ -243,20 +632,16 @@ func (lg *Logger) Emergencyf(format string, v ...interface{}) {
 
 // Log logs with the given severity. v must be either a string, or something that
 // marshals via the encoding/json package to a JSON object (and not any other type
-// of JSON value).
-func (lg *Logger) Log(severity logging.Severity, v interface{}) {
+// of JSON value). The exception is a []byte, which is logged as an object whose
+// BytesField field holds its base64 encoding, or as text if the Logger was created
+// with WithBytesAsText and it is valid UTF-8.
+func (lg *Logger) Log(severity Severity, v interface{}) {
 	if lg.logger == nil {
-		log.Print(v)
+		log.Print(lg.config().fallbackPayload(v))
 		return
 	}
 
-	lg.logger.Log(logging.Entry{
-		Timestamp: time.Now(),
-		Severity:  severity,
-		Payload:   v,
-		Trace:     lg.trace,
-		Resource:  lg.monRes,
-	})
+	lg.write(lg.entry(severity, v))
 }
 
 // Debug calls Log with debug severity.
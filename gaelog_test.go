@@ -1,12 +1,17 @@
 package gaelog
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 
+	"cloud.google.com/go/logging"
 	"github.com/kylelemons/godebug/pretty"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
@@ -20,8 +25,38 @@ const (
 	// testProjectIDMetadataServer is a different project ID that is returned from
 	// the metadata server mock so that the source of the ID may be distinguished.
 	testProjectIDMetadataServer = "my-project-from-metadata-server"
+	testNumericProjectID        = "123456789012"
+	testInstanceID              = "00bf4bf02d3a4c5f"
 )
 
+// TestMain mocks the metadata server for all tests. Besides serving the project ID, this makes
+// the metadata package (which memoizes whether it's running on GCE) and, in turn, the Stackdriver
+// Logging client's credential lookup behave as they would on GCP, regardless of test order.
+// Individual tests may point $GCE_METADATA_HOST elsewhere but must restore it when done.
+func TestMain(m *testing.M) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte(testProjectIDMetadataServer))
+		case "/computeMetadata/v1/project/numeric-project-id":
+			w.Write([]byte(testNumericProjectID))
+		case "/computeMetadata/v1/instance/id":
+			w.Write([]byte(testInstanceID))
+		case "/computeMetadata/v1/":
+			w.Write([]byte(""))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	// The metadata package prepends the protocol so strip it off here.
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
 func setEnvVars(vars map[string]string) func() {
 	for k, v := range vars {
 		os.Setenv(k, v)
@@ -34,6 +69,57 @@ func setEnvVars(vars map[string]string) func() {
 	}
 }
 
+// setGAEEnvVars sets the environment variables present on App Engine for the duration of the test.
+func setGAEEnvVars(t testing.TB) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	t.Cleanup(unset)
+}
+
+// newRedirectedLogger returns a Logger configured as on App Engine whose entries are written as
+// JSON to the returned buffer instead of being sent to Stackdriver Logging.
+func newRedirectedLogger(t *testing.T, options ...Option) (*Logger, *bytes.Buffer) {
+	t.Helper()
+
+	setGAEEnvVars(t)
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	var buf bytes.Buffer
+	options = append([]Option{WithLoggerOptions(logging.RedirectAsJSON(&buf))}, options...)
+	lg, err := NewWithOptions(r, options...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { lg.Close() })
+
+	return lg, &buf
+}
+
+// decodeEntries decodes the JSON entries written by a Logger made with newRedirectedLogger. The
+// instrumentation entry that the Stackdriver Logging client writes once per process is skipped.
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("Failed to decode entry: %v", err)
+		}
+		if m, ok := e["message"].(map[string]interface{}); ok && m["logging.googleapis.com/diagnostic"] != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
 func TestTraceID(t *testing.T) {
 	got := traceID(testProjectID, "abcdef0123456789")
 	expected := "projects/" + testProjectID + "/traces/abcdef0123456789"
@@ -42,6 +128,59 @@ func TestTraceID(t *testing.T) {
 	}
 }
 
+func TestParseTraceContext(t *testing.T) {
+	cases := []struct {
+		header      string
+		wantTrace   string
+		wantSpan    string
+		wantSampled bool
+	}{
+		{"abcdef0123456789/123;o=1", "abcdef0123456789", "123", true},
+		{"abcdef0123456789/123;o=0", "abcdef0123456789", "123", false},
+		{"abcdef0123456789/123", "abcdef0123456789", "123", false},
+		{"abcdef0123456789", "abcdef0123456789", "", false},
+		{"abcdef0123456789/123;o=1,fedcba9876543210/456;o=0", "abcdef0123456789", "123", true},
+		{" abcdef0123456789/123 , fedcba9876543210/456", "abcdef0123456789", "123", false},
+	}
+
+	for _, c := range cases {
+		trace, span, sampled := parseTraceContext(c.header)
+		if trace != c.wantTrace || span != c.wantSpan || sampled != c.wantSampled {
+			t.Errorf("parseTraceContext(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				c.header, trace, span, sampled, c.wantTrace, c.wantSpan, c.wantSampled)
+		}
+	}
+}
+
+func TestDetectServiceInfoCloudRun(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"K_SERVICE":       testServiceID,
+		"K_REVISION":      testVersionID,
+		"K_CONFIGURATION": testConfigurationName,
+	})
+	defer unset()
+
+	resolved := func() (string, error) { return "resolved-project", nil }
+	failed := func() (string, error) { return "", errors.New("no metadata server") }
+
+	info, err := detectServiceInfo(defaultConfig, resolved)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.projectID != "resolved-project" || info.resource.Type != CloudRunResourceType {
+		t.Errorf("Expected a Cloud Run resource in the resolved project, got %q and %v", info.projectID, info.resource)
+	}
+
+	if _, err := detectServiceInfo(defaultConfig, failed); err == nil || err.Error() != "no metadata server" {
+		t.Errorf("Expected the resolver's error, got %v", err)
+	}
+
+	cfg := newConfig([]Option{WithProjectID("explicit-project")})
+	if info, err := detectServiceInfo(cfg, failed); err != nil || info.projectID != "explicit-project" {
+		t.Errorf("Expected the resolver not to be called given a project ID, got %q (%v)", info.projectID, err)
+	}
+}
+
 func TestNew(t *testing.T) {
 	// Mock the metadata service.
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -59,7 +198,7 @@ func TestNew(t *testing.T) {
 	// If it is set, the metadata package uses $GCE_METADATA_HOST instead of its
 	// hard-coded IP of the service. The metadata package prepends the protocol
 	// so strip it off here.
-	defer os.Unsetenv("GCE_METADATA_HOST")
+	defer os.Setenv("GCE_METADATA_HOST", os.Getenv("GCE_METADATA_HOST"))
 	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
 
 	cases := []struct {
@@ -89,6 +228,25 @@ func TestNew(t *testing.T) {
 			},
 			"",
 		},
+		{
+			"gae_flex_env_vars_with_header",
+			map[string]string{
+				"GOOGLE_CLOUD_PROJECT": testProjectID,
+				"GAE_SERVICE":          testServiceID,
+				"GAE_VERSION":          testVersionID,
+				"GAE_INSTANCE":         "aef-default-1-abcd",
+			},
+			true,
+			&monitoredres.MonitoredResource{
+				Labels: map[string]string{
+					"module_id":  testServiceID,
+					"project_id": testProjectID,
+					"version_id": testVersionID,
+				},
+				Type: "gae_app",
+			},
+			"",
+		},
 		{
 			"incomplete_gae_env_vars_with_header",
 			map[string]string{
@@ -195,3 +353,149 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCommaSeparatedTraceContext(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1,fedcba9876543210/456;o=1")
+
+	lg, err := New(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer lg.Close()
+
+	want := "projects/" + testProjectID + "/traces/abcdef0123456789"
+	if lg.trace != want {
+		t.Errorf("Expected trace %q, got %q", want, lg.trace)
+	}
+}
+
+func TestNumericProjectID(t *testing.T) {
+	got, err := NumericProjectID()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != testNumericProjectID {
+		t.Errorf("Expected %q, got %q", testNumericProjectID, got)
+	}
+}
+
+func TestNewOrFallback(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// No env vars are set, so the Logger falls back and the error is logged.
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	lg := NewOrFallback(r)
+	if lg == nil {
+		t.Fatalf("Expected non-nil Logger")
+	}
+	if lg.logger != nil {
+		t.Errorf("Expected fallback Logger")
+	}
+
+	if !strings.Contains(buf.String(), "GAE env vars were not set") {
+		t.Errorf("Expected setup error to be logged, got %q", buf.String())
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	cases := []struct {
+		format string
+		v      []interface{}
+		want   string
+	}{
+		{"plain", nil, "plain"},
+		{"100%%", nil, "100%"},
+		{"%d%%", []interface{}{50}, "50%"},
+	}
+	for _, c := range cases {
+		if got := sprintf(c.format, c.v...); got != c.want {
+			t.Errorf("sprintf(%q, %v) = %q, want %q", c.format, c.v, got, c.want)
+		}
+	}
+}
+
+func TestNewGAEInstanceLabel(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    string
+		expect bool
+	}{
+		{"flex", "", true},
+		{"standard", "standard", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+			t.Setenv("GAE_INSTANCE", "aef-default-1-abcd")
+			t.Setenv("GAE_ENV", c.env)
+
+			lg, buf := newRedirectedLogger(t, WithLabels(map[string]string{"a": "b"}))
+			lg.Info("hi")
+
+			entries := decodeEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+
+			labels, _ := entries[0]["logging.googleapis.com/labels"].(map[string]interface{})
+			if got := labels[GAEInstanceLabel] == "aef-default-1-abcd"; got != c.expect {
+				t.Errorf("Expected instance label: %v, got labels %v", c.expect, labels)
+			}
+			if labels["a"] != "b" {
+				t.Errorf("Expected the given labels to be kept, got %v", labels)
+			}
+		})
+	}
+}
+
+func TestNewCrossProject(t *testing.T) {
+	cases := []struct {
+		name            string
+		options         []Option
+		parent          string
+		resourceProject string
+	}{
+		{"default", nil, "projects/" + testProjectID, testProjectID},
+		{"log_project", []Option{WithLogProjectID("central-logs")}, "projects/central-logs", testProjectID},
+		{"project", []Option{WithProjectID("other-app")}, "projects/other-app", "other-app"},
+		{"both", []Option{WithProjectID("other-app"), WithLogProjectID("central-logs")}, "projects/central-logs", "other-app"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lg, _ := newRedirectedLogger(t, c.options...)
+
+			if lg.parent != c.parent {
+				t.Errorf("Expected parent %q, got %q", c.parent, lg.parent)
+			}
+			if got := lg.monRes.Labels["project_id"]; got != c.resourceProject {
+				t.Errorf("Expected resource project_id %q, got %q", c.resourceProject, got)
+			}
+			if want := traceID(c.resourceProject, "abcdef0123456789"); lg.trace != want {
+				t.Errorf("Expected trace %q, got %q", want, lg.trace)
+			}
+		})
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	lg, _ := newRedirectedLogger(t)
+
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := lg.Close(); err != nil {
+		t.Errorf("Expected closing again to be safe, got %v", err)
+	}
+}
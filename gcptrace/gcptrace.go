@@ -0,0 +1,63 @@
+// Package gcptrace writes the spans of gaelog's Loggers to Cloud Trace. It is separate from gaelog
+// so that apps that don't use it don't depend on the Cloud Trace client.
+package gcptrace
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mtraver/gaelog"
+	cloudtrace "google.golang.org/api/cloudtrace/v2"
+)
+
+// WithCloudTrace returns a gaelog.Option that writes a span for each request to Cloud Trace using
+// svc. The span is a child of the span in the request's trace context and covers the time from the
+// creation of the request's Logger to its closing. For wrapped handlers it is labeled with the
+// response's HTTP status code. A request thus shows up in Cloud Trace, with its latency and status,
+// even if the app isn't otherwise instrumented:
+//
+//	svc, err := cloudtrace.NewService(ctx)
+//	...
+//	http.Handle("/", gaelog.WrapWithOptions(h, gcptrace.WithCloudTrace(svc)))
+//
+// The span is written when the Logger is closed, which adds a call to the Cloud Trace API to the
+// end of every request, as well as the cost of the spans themselves. See gaelog.WithSpanWriter.
+func WithCloudTrace(svc *cloudtrace.Service) gaelog.Option {
+	return gaelog.WithSpanWriter(NewSpanWriter(svc))
+}
+
+// NewSpanWriter returns a gaelog.SpanWriter that writes spans to Cloud Trace using svc.
+func NewSpanWriter(svc *cloudtrace.Service) gaelog.SpanWriter {
+	return spanWriter{svc: svc}
+}
+
+type spanWriter struct {
+	svc *cloudtrace.Service
+}
+
+func (w spanWriter) WriteSpan(ctx context.Context, s gaelog.Span) error {
+	project := "projects/" + s.ProjectID
+	span := &cloudtrace.Span{
+		Name:         fmt.Sprintf("%s/traces/%s/spans/%s", project, s.TraceID, s.SpanID),
+		SpanId:       s.SpanID,
+		ParentSpanId: s.ParentSpanID,
+		DisplayName:  &cloudtrace.TruncatableString{Value: s.Name},
+		StartTime:    s.Start.Format(time.RFC3339Nano),
+		EndTime:      s.End.Format(time.RFC3339Nano),
+		SpanKind:     "SERVER",
+	}
+	if s.Status != 0 {
+		span.Attributes = &cloudtrace.Attributes{
+			AttributeMap: map[string]cloudtrace.AttributeValue{
+				"/http/status_code": {IntValue: int64(s.Status)},
+			},
+		}
+	}
+
+	req := &cloudtrace.BatchWriteSpansRequest{Spans: []*cloudtrace.Span{span}}
+	if _, err := w.svc.Projects.Traces.BatchWrite(project, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("gcptrace: failed to write span: %v", err)
+	}
+	return nil
+}
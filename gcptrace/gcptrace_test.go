@@ -0,0 +1,69 @@
+package gcptrace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	"github.com/mtraver/gaelog"
+	cloudtrace "google.golang.org/api/cloudtrace/v2"
+	"google.golang.org/api/option"
+)
+
+type nopSink struct{}
+
+func (nopSink) Log(e logging.Entry) {}
+
+func TestWithCloudTrace(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+	t.Setenv("GAE_SERVICE", "default")
+	t.Setenv("GAE_VERSION", "1")
+
+	var path string
+	var got cloudtrace.BatchWriteSpansRequest
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer api.Close()
+
+	svc, err := cloudtrace.NewService(context.Background(), option.WithEndpoint(api.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handler := gaelog.WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), WithCloudTrace(svc), gaelog.WithSink(nopSink{}))
+
+	req := httptest.NewRequest("GET", "http://example.com/brew", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "4bf92f3577b34da6a3ce929d0e0e4736/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if path != "/v2/projects/my-project/traces:batchWrite" {
+		t.Errorf("Unexpected request path %q", path)
+	}
+	if len(got.Spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(got.Spans))
+	}
+
+	s := got.Spans[0]
+	if want := "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736/spans/" + s.SpanId; s.Name != want {
+		t.Errorf("Expected name %q, got %q", want, s.Name)
+	}
+	if s.ParentSpanId != "000000000000007b" {
+		t.Errorf("Expected the inbound span as parent, got %q", s.ParentSpanId)
+	}
+	if s.DisplayName == nil || s.DisplayName.Value != "/brew" {
+		t.Errorf("Expected display name /brew, got %v", s.DisplayName)
+	}
+	if s.Attributes == nil || s.Attributes.AttributeMap["/http/status_code"].IntValue != http.StatusTeapot {
+		t.Errorf("Expected the status code attribute, got %v", s.Attributes)
+	}
+}
@@ -0,0 +1,41 @@
+package gaelog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// GoroutineLabel is the key of the label set by WithGoroutineLabel.
+const GoroutineLabel = "goroutine"
+
+// WithGoroutineLabel sets a label with key GoroutineLabel on every entry logged by the Logger, whose
+// value is the ID of the goroutine that logged it. This helps to untangle the entries of a
+// request that does work concurrently.
+//
+// This is for debugging only. Go deliberately does not expose goroutine IDs, so the ID is parsed
+// from the output of runtime.Stack, which is slow and whose format is not guaranteed to stay the
+// same; if it cannot be parsed then the label is omitted. Goroutine IDs are also reused, so they
+// identify a goroutine only among those running at the same time.
+func WithGoroutineLabel() Option {
+	return func(cfg *config) {
+		cfg.goroutineLabel = true
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, or the empty string if it cannot be
+// determined.
+func goroutineID() string {
+	// The stack trace begins "goroutine 123 [running]:".
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	if _, err := strconv.ParseUint(string(b), 10, 64); err != nil {
+		return ""
+	}
+	return string(b)
+}
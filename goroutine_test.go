@@ -0,0 +1,33 @@
+package gaelog
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		t.Fatalf("Expected a numeric goroutine ID, got %q", id)
+	}
+
+	other := make(chan string)
+	go func() { other <- goroutineID() }()
+	if got := <-other; got == id || got == "" {
+		t.Errorf("Expected another goroutine to have a different ID than %q, got %q", id, got)
+	}
+}
+
+func TestWithGoroutineLabel(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithGoroutineLabel())
+	lg.Info("hi")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	labels, _ := entries[0]["logging.googleapis.com/labels"].(map[string]interface{})
+	if labels[GoroutineLabel] != goroutineID() {
+		t.Errorf("Expected label %q to be %q, got %v", GoroutineLabel, goroutineID(), labels)
+	}
+}
@@ -0,0 +1,87 @@
+package gaelog
+
+import (
+	"net/http"
+	"time"
+)
+
+// A Handler is like the http.Handler returned by WrapWithOptions, but rather than creating a
+// Stackdriver Logging client for each request it creates one when it is constructed and shares it
+// among all requests. The client lives until the Handler is closed, which gives the app explicit
+// control over the startup and shutdown of logging:
+//
+//	h, err := gaelog.NewHandler(mux)
+//	if err != nil {
+//		log.Printf("Logging will fall back to the standard library: %v", err)
+//	}
+//	defer h.Close()
+type Handler struct {
+	h   http.Handler
+	cfg *config
+
+	// owner owns the client shared by the Loggers of all requests. It is not correlated with any
+	// request and is in fallback mode if the Handler is.
+	owner *Logger
+}
+
+// NewHandler returns a Handler that serves requests with inner, which may log using the request's
+// context as with WrapWithOptions. The Options are those accepted by WrapWithOptions.
+//
+// The Handler is valid in all cases, even when the error is non-nil. In the case of a non-nil error
+// the Loggers it makes fall back to the standard library's "log" package; the cases are those
+// described in NewWithID, except that a request without the X-Cloud-Trace-Context header causes
+// only that request's Logger to fall back. If the Handler is created with WithLazyClient then an
+// error creating the client is not returned.
+func NewHandler(inner http.Handler, options ...Option) (*Handler, error) {
+	cfg := newConfig(options)
+	h := &Handler{
+		h:     inner,
+		cfg:   cfg,
+		owner: &Logger{cfg: cfg},
+	}
+
+	info, err := newServiceInfo(cfg)
+	if err != nil {
+		return h, err
+	}
+
+	owner := &Logger{cfg: cfg, parent: cfg.parent(info), service: info.service}
+	if err := owner.open(); err != nil {
+		return h, err
+	}
+	owner.track()
+	h.owner = owner
+	return h, nil
+}
+
+// ServeHTTP serves the request with the Handler's inner handler. The request's Logger uses the
+// Handler's client, so it must not be called after the Handler is closed.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	logger, err := newRequestLogger(r, h.cfg)
+	if err == nil && !h.owner.IsFallback() {
+		logger.client = h.owner.client
+		logger.lazy = h.owner.lazy
+		logger.logger = h.owner.logger
+		logger.shared = true
+		logger.sample()
+	}
+	defer logger.Close()
+
+	serve(h.h, h.cfg, logger, start, w, r)
+}
+
+// Flush sends any buffered entries to Stackdriver Logging, returning once they have been sent.
+func (h *Handler) Flush() error {
+	if h.owner.IsFallback() {
+		return nil
+	}
+	return h.owner.logger.Flush()
+}
+
+// Close flushes any buffered entries and closes the Handler's Stackdriver Logging client. It
+// should be called when the app shuts down, once the Handler has stopped serving requests.
+func (h *Handler) Close() error {
+	return h.owner.Close()
+}
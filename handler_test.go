@@ -0,0 +1,104 @@
+package gaelog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestHandler(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	h, err := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Infof(r.Context(), "hello %s", r.URL.Path)
+	}), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"/a", "/b"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if h.owner.client == nil {
+		t.Fatal("Expected the Handler to have a client")
+	}
+	if err := h.Flush(); err != nil {
+		t.Errorf("Unexpected error flushing: %v", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("Unexpected error closing: %v", err)
+	}
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"hello /a", "hello /b"} {
+		if entries[i]["message"] != want {
+			t.Errorf("Expected message %q, got %v", want, entries[i]["message"])
+		}
+		if trace := entries[i]["logging.googleapis.com/trace"]; trace != traceID(testProjectID, "abcdef0123456789") {
+			t.Errorf("Unexpected trace %v", trace)
+		}
+	}
+}
+
+func TestHandlerRequestLoggerSharesClient(t *testing.T) {
+	setGAEEnvVars(t)
+
+	cl := &countingLogger{}
+	h, err := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Info(r.Context(), "hello")
+	}), WithSink(cl))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Make the Handler's logger count flushes.
+	h.owner.logger = cl
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if cl.flushes != 0 {
+		t.Errorf("Expected the request's Logger to leave flushing to the Handler, got %d flushes", cl.flushes)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Errorf("Unexpected error closing: %v", err)
+	}
+	if cl.flushes != 1 {
+		t.Errorf("Expected closing the Handler to flush, got %d flushes", cl.flushes)
+	}
+}
+
+func TestHandlerFallback(t *testing.T) {
+	// No env vars are set, so the Handler falls back.
+	var fallback bool
+	h, err := NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallback = IsFallback(r.Context())
+	}))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !fallback {
+		t.Error("Expected the request's Logger to fall back")
+	}
+
+	if err := h.Flush(); err != nil {
+		t.Errorf("Unexpected error flushing: %v", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("Unexpected error closing: %v", err)
+	}
+}
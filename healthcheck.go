@@ -0,0 +1,45 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// healthCheckTimeout bounds the health check made by Loggers created with WithHealthCheck.
+const healthCheckTimeout = 5 * time.Second
+
+// WithHealthCheck makes the Logger check that it can write to Stackdriver Logging when its client
+// is created, by writing an empty entry (see logging.Client.Ping). A client can be created even
+// when writing will fail, such as because the Cloud Logging API is disabled or the service account
+// lacks permission, in which case entries are otherwise dropped with only the error handler to
+// tell. With the check, a Logger that can't write is instead created in fallback mode, with an
+// error saying why, as described in NewWithID.
+//
+// The check is a round trip to Cloud Logging, bounded by a timeout of a few seconds, made whenever
+// a client is created. Wrapped handlers create a client for each request, so it is best combined
+// with NewHandler, which creates one client up front. It has no effect on Loggers created with
+// WithLazyClient, WithEncoder, or WithSink.
+func WithHealthCheck() Option {
+	return func(cfg *config) {
+		cfg.healthCheck = true
+	}
+}
+
+// checkHealth pings client if cfg calls for it, closing client and returning an error if the ping
+// fails.
+func (cfg *config) checkHealth(client *logging.Client) error {
+	if !cfg.healthCheck {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(cfg.clientContext, healthCheckTimeout)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("gaelog: health check of Stackdriver Logging client failed, falling back to standard library log: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package gaelog
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithHealthCheck(t *testing.T) {
+	setGAEEnvVars(t)
+
+	for _, c := range []struct {
+		name    string
+		healthy bool
+	}{
+		{"healthy", true},
+		{"forbidden", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f, addr := startFakeEmulator(t)
+			if !c.healthy {
+				f.mu.Lock()
+				f.writeErr = status.Error(codes.PermissionDenied, "logging.logEntries.create denied")
+				f.mu.Unlock()
+			}
+
+			r := httptest.NewRequest("GET", "https://example.com", nil)
+			r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			lg, err := NewWithOptions(r, WithEmulatorHost(addr), WithHealthCheck())
+			defer lg.Close()
+
+			if c.healthy && (err != nil || lg.IsFallback()) {
+				t.Errorf("Expected a working Logger, got error %v", err)
+			}
+			if !c.healthy && (err == nil || !lg.IsFallback()) {
+				t.Errorf("Expected the Logger to fall back with an error, got error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package gaelog
+
+import (
+	"sync"
+	"time"
+)
+
+// StartHeartbeat logs a small entry with lg at the given severity every interval until the
+// returned function is called, so that a long-running instance, such as one serving streams on
+// Cloud Run, can be seen to be alive and its logging to be working. An instance whose heartbeats
+// stop while it is still running is likely stuck. Each entry's payload has the fields "message",
+// which is "heartbeat", and "count", which is the number of heartbeats so far, starting from 1.
+//
+// The returned function stops the heartbeat, waiting for any entry being logged to be written. It
+// may be called more than once and must be called before lg is closed.
+func StartHeartbeat(lg *Logger, interval time.Duration, severity Severity) (stop func()) {
+	count := 0
+	return every(interval, func() {
+		count++
+		lg.Log(severity, map[string]interface{}{
+			"message": "heartbeat",
+			"count":   count,
+		})
+	})
+}
+
+// every calls fn every interval, from a new goroutine, until the returned function is called. The
+// returned function waits for any call to fn to return and may be called more than once.
+func every(interval time.Duration, fn func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopped) })
+		<-exited
+	}
+}
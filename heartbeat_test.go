@@ -0,0 +1,32 @@
+package gaelog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartHeartbeat(t *testing.T) {
+	rec := &recordingLogger{}
+	lg := &Logger{cfg: defaultConfig, logger: rec}
+
+	stop := StartHeartbeat(lg, time.Millisecond, SeverityInfo)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	stop()
+
+	n := len(rec.payloads)
+	if n == 0 {
+		t.Fatalf("Expected heartbeats to be logged")
+	}
+	for i, p := range rec.payloads {
+		m, ok := p.(map[string]interface{})
+		if !ok || m["message"] != "heartbeat" || m["count"] != i+1 {
+			t.Errorf("Unexpected heartbeat payload %d: %v", i, p)
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if len(rec.payloads) != n {
+		t.Errorf("Expected no heartbeats once stopped")
+	}
+}
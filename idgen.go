@@ -0,0 +1,66 @@
+package gaelog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// An IDGenerator generates the IDs that Loggers make up, such as the span IDs of WithSpans.
+type IDGenerator interface {
+	// NewID returns a new ID of n bytes, encoded as 2n lowercase hexadecimal digits. IDs should
+	// be unique among those in use at the same time; in particular, they must not be all zeros,
+	// which Cloud Trace treats as invalid.
+	NewID(n int) string
+}
+
+// RandomIDGenerator generates IDs from crypto/rand. It is the default.
+var RandomIDGenerator IDGenerator = NewReaderIDGenerator(rand.Reader)
+
+// WithIDGenerator sets the IDGenerator used by the Logger, such as to make IDs deterministic in
+// tests or to draw them from a particular source of entropy. If this option is not given then
+// RandomIDGenerator is used.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(cfg *config) {
+		cfg.idGenerator = g
+	}
+}
+
+// NewReaderIDGenerator returns an IDGenerator that reads the bytes of IDs from r, which it only
+// reads from one goroutine at a time. For example, a math/rand.Rand with a fixed seed generates
+// the same IDs on every run. If reading from r fails then an ID is derived from the current time.
+func NewReaderIDGenerator(r io.Reader) IDGenerator {
+	return &readerIDGenerator{r: r}
+}
+
+type readerIDGenerator struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (g *readerIDGenerator) NewID(n int) string {
+	b := make([]byte, n)
+
+	g.mu.Lock()
+	_, err := io.ReadFull(g.r, b)
+	g.mu.Unlock()
+
+	if err != nil {
+		// A time-based ID is still unique enough to group a request's entries. Keep the low-order
+		// digits, which change fastest, if the time has more than the ID calls for.
+		id := fmt.Sprintf("%0*x", 2*n, time.Now().UnixNano())
+		return id[len(id)-2*n:]
+	}
+	return hex.EncodeToString(b)
+}
+
+// newSpanID returns a new 16-character hex span ID from the config's IDGenerator.
+func (cfg *config) newSpanID() string {
+	if cfg.idGenerator != nil {
+		return cfg.idGenerator.NewID(8)
+	}
+	return newSpanID()
+}
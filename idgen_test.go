@@ -0,0 +1,61 @@
+package gaelog
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// errReader is an io.Reader whose reads fail.
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+func TestReaderIDGenerator(t *testing.T) {
+	g := NewReaderIDGenerator(bytes.NewReader([]byte{0, 1, 2, 3, 4, 5, 6, 7, 0xf8, 0xf9, 0xfa, 0xfb}))
+	if id := g.NewID(8); id != "0001020304050607" {
+		t.Errorf("Expected 0001020304050607, got %q", id)
+	}
+	if id := g.NewID(4); id != "f8f9fafb" {
+		t.Errorf("Expected f8f9fafb, got %q", id)
+	}
+
+	// Reading fails, so the ID falls back to being derived from the time.
+	re := regexp.MustCompile("^[0-9a-f]{16}$")
+	if id := NewReaderIDGenerator(errReader{}).NewID(8); !re.MatchString(id) {
+		t.Errorf("Expected a 16-character hex ID, got %q", id)
+	}
+
+	// The time has more digits than a 2-byte ID, so it is truncated.
+	re = regexp.MustCompile("^[0-9a-f]{4}$")
+	if id := NewReaderIDGenerator(errReader{}).NewID(2); !re.MatchString(id) {
+		t.Errorf("Expected a 4-character hex ID, got %q", id)
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com/foo", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/255;o=1")
+
+	g := NewReaderIDGenerator(bytes.NewReader(bytes.Repeat([]byte{0xab}, 8)))
+	lg, err := NewWithOptions(r, WithSpans(), WithIDGenerator(g), WithSink(nopLogger{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer lg.Close()
+
+	if lg.spanID != "abababababababab" {
+		t.Errorf("Expected span ID abababababababab, got %q", lg.spanID)
+	}
+}
@@ -0,0 +1,88 @@
+package gaelog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// A LabelPair is a label's key and its value formatted as a string. Make one with Label.
+type LabelPair struct {
+	Key   string
+	Value string
+}
+
+// Label returns a label with the given key whose value is v formatted consistently regardless of
+// its type, for labels whose values aren't strings to begin with:
+//
+//   - bools are formatted as "true" or "false"
+//   - integers are formatted in base 10
+//   - floats are formatted with the fewest digits that represent them exactly, e.g. "0.1"
+//   - time.Durations are formatted as by their String method, e.g. "1.5s"
+//   - time.Times are formatted as RFC 3339 with nanoseconds (time.RFC3339Nano), as by Time
+//   - errors and fmt.Stringers are formatted as by their Error and String methods
+//
+// Other values are formatted as by fmt.Sprint. Use Labels to collect labels into a map, such as
+// for WithLabels:
+//
+//	gaelog.WithLabels(gaelog.Labels(
+//		gaelog.Label("shard", 3),
+//		gaelog.Label("canary", true),
+//	))
+func Label[T any](key string, v T) LabelPair {
+	return LabelPair{Key: key, Value: formatLabelValue(v)}
+}
+
+// Labels returns a map of the given labels, as used by WithLabels and logging.Entry. If more than
+// one label has the same key then the last one's value is used.
+func Labels(labels ...LabelPair) map[string]string {
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		m[l.Key] = l.Value
+	}
+	return m
+}
+
+func formatLabelValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case uintptr:
+		return strconv.FormatUint(uint64(v), 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
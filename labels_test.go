@@ -0,0 +1,55 @@
+package gaelog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type shard int
+
+func TestLabel(t *testing.T) {
+	cases := []struct {
+		name string
+		got  LabelPair
+		want string
+	}{
+		{"string", Label("k", "v"), "v"},
+		{"true", Label("k", true), "true"},
+		{"false", Label("k", false), "false"},
+		{"int", Label("k", -42), "-42"},
+		{"int64", Label("k", int64(1)<<40), "1099511627776"},
+		{"uint8", Label("k", uint8(255)), "255"},
+		{"uint64", Label("k", uint64(1)<<63), "9223372036854775808"},
+		{"float64", Label("k", 0.1), "0.1"},
+		{"float64_whole", Label("k", 2.0), "2"},
+		{"float64_large", Label("k", 1e21), "1e+21"},
+		{"float32", Label("k", float32(0.1)), "0.1"},
+		{"duration", Label("k", 1500*time.Millisecond), "1.5s"},
+		{"time", Label("k", time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)), "2020-01-02T03:04:05.000000006Z"},
+		{"error", Label("k", errors.New("oh no")), "oh no"},
+		{"named_int", Label("k", shard(3)), "3"},
+		{"slice", Label("k", []int{1, 2}), "[1 2]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.got.Key != "k" {
+				t.Errorf("Expected key %q, got %q", "k", c.got.Key)
+			}
+			if c.got.Value != c.want {
+				t.Errorf("Expected value %q, got %q", c.want, c.got.Value)
+			}
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	got := Labels(Label("shard", 3), Label("canary", true), Label("shard", 4))
+	want := map[string]string{"shard": "4", "canary": "true"}
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("Unexpected result (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,64 @@
+package gaelog
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithLatencyBreakdown adds a breakdown of the request's latency to the entry logged by
+// WithRequestSummary in the SummaryStructured format, to help tell whether a slow request was slow
+// in the handler or in producing the first byte of the response. The entry's payload becomes an
+// object whose "message" field is the usual summary message and whose "latency" field is an object
+// with the fields:
+//
+//   - "timeToFirstByte": from when the request began to be handled until the status was written,
+//     omitted if the handler wrote no response
+//   - "handler": the time spent in the wrapped handler
+//   - "middleware": the time spent outside the wrapped handler, such as in creating its Logger
+//
+// Durations are formatted as seconds with a trailing "s", e.g. "0.012500000s", as is the latency
+// of the entry's HTTPRequest field. It has no effect without WithRequestSummary.
+func WithLatencyBreakdown() Option {
+	return func(cfg *config) {
+		cfg.latencyBreakdown = true
+	}
+}
+
+// latencyBreakdown divides the latency of a request as described in WithLatencyBreakdown.
+type latencyBreakdown struct {
+	// timeToFirstByte is zero if no response was written.
+	timeToFirstByte time.Duration
+	handler         time.Duration
+	middleware      time.Duration
+}
+
+// newLatencyBreakdown returns the breakdown of the latency of a request that began to be handled
+// at start and whose handler took the given time, with w being the ResponseWriter it wrote to.
+func newLatencyBreakdown(start time.Time, latency, handler time.Duration, w *responseWriter) *latencyBreakdown {
+	b := &latencyBreakdown{
+		handler:    handler,
+		middleware: latency - handler,
+	}
+	if !w.firstByte.IsZero() {
+		b.timeToFirstByte = w.firstByte.Sub(start)
+	}
+	return b
+}
+
+// fields returns the breakdown as the "latency" field of the summary's payload.
+func (b *latencyBreakdown) fields() map[string]string {
+	fields := map[string]string{
+		"handler":    formatDuration(b.handler),
+		"middleware": formatDuration(b.middleware),
+	}
+	if b.timeToFirstByte != 0 {
+		fields["timeToFirstByte"] = formatDuration(b.timeToFirstByte)
+	}
+	return fields
+}
+
+// formatDuration formats d as a number of seconds with a trailing "s", the format of durations in
+// Cloud Logging's JSON representation of entries.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.9fs", d.Seconds())
+}
@@ -0,0 +1,67 @@
+package gaelog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithLatencyBreakdown(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("hello"))
+		time.Sleep(5 * time.Millisecond)
+	}), WithRequestSummary(), WithLatencyBreakdown(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	payload, ok := rec.entries[0].Payload.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected an object payload, got %v", rec.entries[0].Payload)
+	}
+	fields, _ := payload["latency"].(map[string]string)
+
+	parse := func(key string) time.Duration {
+		s := fields[key]
+		if len(s) == 0 || s[len(s)-1] != 's' {
+			t.Fatalf("Expected %s to be a duration in seconds, got %q", key, s)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", key, err)
+		}
+		return d
+	}
+	ttfb, h, m := parse("timeToFirstByte"), parse("handler"), parse("middleware")
+
+	if ttfb < 5*time.Millisecond || ttfb >= h+m {
+		t.Errorf("Expected time to first byte within the latency, got %v of %v", ttfb, h+m)
+	}
+	if h < 10*time.Millisecond {
+		t.Errorf("Expected handler time of at least 10ms, got %v", h)
+	}
+	if m < 0 {
+		t.Errorf("Expected non-negative middleware time, got %v", m)
+	}
+}
+
+func TestLatencyBreakdownNoResponse(t *testing.T) {
+	b := newLatencyBreakdown(time.Now(), 3*time.Millisecond, 2*time.Millisecond, newResponseWriter(httptest.NewRecorder()))
+
+	fields := b.fields()
+	if _, ok := fields["timeToFirstByte"]; ok {
+		t.Errorf("Expected no time to first byte without a response, got %v", fields)
+	}
+	if fields["handler"] != "0.002000000s" || fields["middleware"] != "0.001000000s" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+}
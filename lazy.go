@@ -0,0 +1,151 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithLazyClient makes logger construction non-blocking. The Stackdriver Logging client is created
+// in the background, and entries logged before it is ready are buffered in memory and sent once it
+// is. At most DefaultMaxBufferedEntries entries are buffered; once the buffer is full the oldest
+// entries are dropped to make room. If creating the client fails then the buffered entries, and
+// all entries logged afterward, are logged using the standard library's log package. This
+// improves latency for the first requests after a cold start, when creating the client can be
+// slow.
+//
+// Because the client is created after the Logger is returned, an error creating it is not returned
+// by NewWithOptions.
+func WithLazyClient() Option {
+	return func(cfg *config) {
+		cfg.lazyClient = true
+	}
+}
+
+// lazyLogger is an entryLogger that creates its client in the background.
+type lazyLogger struct {
+	// done is closed once the client has been created or has failed to be.
+	done chan struct{}
+
+	mu      sync.Mutex
+	client  *logging.Client
+	logger  *logging.Logger
+	err     error
+	pending []logging.Entry
+}
+
+func newLazyLogger(parent, logID string, cfg *config) *lazyLogger {
+	l := &lazyLogger{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		client, err := cfg.newClient(parent)
+		if err != nil {
+			l.ready(nil, nil, err)
+			return
+		}
+		l.ready(client, cfg.clientLogger(client, logID), nil)
+	}()
+
+	return l
+}
+
+// named returns a lazyLogger that logs to the log with the given ID using l's client once it has
+// been created. Closing it does not close the client.
+func (l *lazyLogger) named(logID string, cfg *config) *lazyLogger {
+	n := &lazyLogger{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		<-l.done
+		if l.err != nil {
+			n.ready(nil, nil, l.err)
+			return
+		}
+		n.ready(nil, cfg.clientLogger(l.client, logID), nil)
+	}()
+
+	return n
+}
+
+// ready records the outcome of creating the client and sends the entries buffered in the meantime.
+func (l *lazyLogger) ready(client *logging.Client, logger *logging.Logger, err error) {
+	defer close(l.done)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending := l.pending
+	l.pending = nil
+
+	if err != nil {
+		l.err = err
+		for _, e := range pending {
+			log.Print(e.Payload)
+		}
+		return
+	}
+
+	l.client = client
+	l.logger = logger
+	for _, e := range pending {
+		l.logger.Log(e)
+	}
+}
+
+func (l *lazyLogger) Log(e logging.Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case l.logger != nil:
+		l.logger.Log(e)
+	case l.err != nil:
+		log.Print(e.Payload)
+	default:
+		if len(l.pending) >= DefaultMaxBufferedEntries {
+			l.pending = l.pending[1:]
+			countDrop(dropBufferFull, 1)
+		}
+		l.pending = append(l.pending, e)
+	}
+}
+
+// LogSync waits for the client to be created and then writes the entry synchronously. If the
+// client could not be created then the entry is logged using the standard library's log package.
+func (l *lazyLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if l.err != nil {
+		log.Print(e.Payload)
+		return nil
+	}
+	return l.logger.LogSync(ctx, e)
+}
+
+// Flush waits for the client to be created and then flushes it. If the client could not be created
+// then the error from doing so is returned.
+func (l *lazyLogger) Flush() error {
+	<-l.done
+	if l.err != nil {
+		return l.err
+	}
+	return l.logger.Flush()
+}
+
+// Close waits for the client to be created and then closes it, ensuring all logs are flushed.
+func (l *lazyLogger) Close() error {
+	<-l.done
+	if l.client == nil {
+		return nil
+	}
+	return l.client.Close()
+}
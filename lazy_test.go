@@ -0,0 +1,52 @@
+package gaelog
+
+import (
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestWithLazyClient(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithLazyClient())
+	if lg.lazy == nil {
+		t.Fatalf("Expected lazy logger")
+	}
+
+	for _, msg := range []string{"one", "two", "three"} {
+		lg.Info(msg)
+	}
+
+	if err := lg.logger.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		if got := entries[i]["message"]; got != want {
+			t.Errorf("Expected entry %d to be %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestLazyLoggerBufferLimit(t *testing.T) {
+	dropCounts[dropBufferFull].Store(0)
+
+	// The client is never created, so entries stay buffered.
+	l := &lazyLogger{done: make(chan struct{})}
+	for i := 0; i <= DefaultMaxBufferedEntries; i++ {
+		l.Log(logging.Entry{Payload: i})
+	}
+
+	if len(l.pending) != DefaultMaxBufferedEntries {
+		t.Errorf("Expected %d buffered entries, got %d", DefaultMaxBufferedEntries, len(l.pending))
+	}
+	if got := l.pending[0].Payload; got != 1 {
+		t.Errorf("Expected the oldest entry to be dropped, got first entry %v", got)
+	}
+	if n := dropCounts[dropBufferFull].Load(); n != 1 {
+		t.Errorf("Expected 1 entry counted as dropped, got %d", n)
+	}
+}
@@ -0,0 +1,141 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// NewLogr returns a logr.Logger that logs with lg, for libraries that log using go-logr. Each
+// call to Info or Error logs a single entry whose payload holds the message in its "message" field
+// and the key/value pairs as further fields:
+//
+//	log := gaelog.NewLogr(lg).WithName("cache")
+//	log.Info("evicted", "key", k, "age", age)
+//
+// Info logs at SeverityInfo at verbosity 0 and at SeverityDebug at all greater verbosities. Error
+// logs at SeverityError and sets the "error" field to the error's message. Names given to
+// WithName are joined with "/" and set as the "logger" field. Every verbosity is enabled; use
+// WithMinSeverity to discard debug entries.
+func NewLogr(lg *Logger) logr.Logger {
+	return logr.New(&logrSink{lg: lg})
+}
+
+// LogrFromContext is like NewLogr but logs with the Logger in ctx, applying any entry defaults,
+// prefix, and severity floor that ctx carries. If ctx has no Logger then messages are simply logged
+// using the standard library's log package.
+func LogrFromContext(ctx context.Context) logr.Logger {
+	return logr.New(&logrSink{ctx: ctx})
+}
+
+// logrSink is a logr.LogSink that logs with lg or, if lg is nil, with the Logger in ctx.
+type logrSink struct {
+	lg  *Logger
+	ctx context.Context
+
+	name   string
+	values []interface{}
+
+	// depth is the number of frames between the user's call and the sink, as given by logr in
+	// Init plus any added with WithCallDepth.
+	depth int
+}
+
+var _ logr.CallDepthLogSink = (*logrSink)(nil)
+
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+func (s *logrSink) Enabled(level int) bool {
+	return true
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	severity := SeverityInfo
+	if level > 0 {
+		severity = SeverityDebug
+	}
+	s.log(severity, s.payload(msg, keysAndValues))
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	payload := s.payload(msg, keysAndValues)
+	if err != nil {
+		payload["error"] = err.Error()
+	}
+	s.log(SeverityError, payload)
+}
+
+func (s *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.values = append(append([]interface{}(nil), s.values...), keysAndValues...)
+	return &c
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	c := *s
+	if c.name != "" {
+		name = c.name + "/" + name
+	}
+	c.name = name
+	return &c
+}
+
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.depth += depth
+	return &c
+}
+
+// log logs payload with the source location of the caller of the logr.Logger.
+func (s *logrSink) log(severity Severity, payload map[string]interface{}) {
+	// Skip log itself, the sink method that called it, and the frames added by logr.
+	depth := s.depth + 2
+
+	if s.lg != nil {
+		s.lg.logDepth(context.Background(), severity, depth, payload)
+		return
+	}
+
+	logger := loggerFromContext(s.ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	logger.logDepth(s.ctx, severity, depth, payload)
+}
+
+// payload returns the fields of an entry with the given message and key/value pairs, which follow
+// those given to WithValues. A later value for a key replaces an earlier one.
+func (s *logrSink) payload(msg string, keysAndValues []interface{}) map[string]interface{} {
+	fields := map[string]interface{}{"message": msg}
+	if s.name != "" {
+		fields["logger"] = s.name
+	}
+	addLogrValues(fields, s.values)
+	addLogrValues(fields, keysAndValues)
+	return fields
+}
+
+// addLogrValues adds the key/value pairs to fields. A key without a value is given a nil value.
+func addLogrValues(fields map[string]interface{}, keysAndValues []interface{}) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		var v interface{}
+		if i+1 < len(keysAndValues) {
+			v = keysAndValues[i+1]
+		}
+		switch x := v.(type) {
+		case logr.Marshaler:
+			v = x.MarshalLog()
+		case error:
+			v = x.Error()
+		}
+		fields[key] = v
+	}
+}
@@ -0,0 +1,78 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestNewLogr(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+	log := NewLogr(lg).WithName("cache").WithValues("shard", 3)
+
+	log.Info("evicted", "key", "k1") // This line is the expected source location.
+	log.V(1).Info("miss", "key", "k2")
+	log.Error(errors.New("boom"), "failed", "dangling")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	cases := []struct {
+		severity string
+		fields   map[string]interface{}
+	}{
+		{"INFO", map[string]interface{}{"message": "evicted", "logger": "cache", "shard": 3.0, "key": "k1"}},
+		{"DEBUG", map[string]interface{}{"message": "miss", "logger": "cache", "shard": 3.0, "key": "k2"}},
+		{"ERROR", map[string]interface{}{"message": "failed", "logger": "cache", "shard": 3.0, "error": "boom", "dangling": nil}},
+	}
+	for i, c := range cases {
+		if got := entries[i]["severity"]; got != c.severity {
+			t.Errorf("Entry %d: expected severity %s, got %v", i, c.severity, got)
+		}
+		fields, _ := entries[i]["message"].(map[string]interface{})
+		for k, want := range c.fields {
+			if got, ok := fields[k]; !ok || got != want {
+				t.Errorf("Entry %d: expected %s to be %v, got %v", i, k, want, got)
+			}
+		}
+	}
+
+	loc, _ := entries[0]["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if fn, _ := loc["function"].(string); !strings.HasSuffix(fn, "TestNewLogr") {
+		t.Errorf("Expected function to be TestNewLogr, got %v", loc)
+	}
+}
+
+func TestLogrFromContext(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LogrFromContext(r.Context()).Info("hello")
+	}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	e := rec.entries[0]
+	if !strings.HasSuffix(e.Trace, "/traces/abcdef0123456789") {
+		t.Errorf("Expected the entry to be correlated with the request, got trace %q", e.Trace)
+	}
+	if e.Severity != logging.Info {
+		t.Errorf("Expected severity %v, got %v", logging.Info, e.Severity)
+	}
+
+	// Without a Logger in the context, messages go to the standard library's logger.
+	LogrFromContext(context.Background()).Info("fallback")
+}
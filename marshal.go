@@ -0,0 +1,34 @@
+package gaelog
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithPayloadCheck makes the Logger marshal object payloads to JSON before sending them. If a
+// payload can't be marshaled, such as because it contains a channel or a cycle, then an entry with
+// SeverityError describing the failure, including the payload's type, is written in its place.
+// Without it such entries are dropped by the Stackdriver Logging client, which reports the error
+// only to the handler set with WithErrorHandler, where a programmer error is easily missed.
+func WithPayloadCheck() Option {
+	return func(cfg *config) {
+		cfg.checkPayloads = true
+	}
+}
+
+// checkPayload returns e unchanged if its payload can be marshaled to JSON. Otherwise it returns e
+// with SeverityError and a payload describing the failure.
+func checkPayload(e logging.Entry) logging.Entry {
+	switch e.Payload.(type) {
+	case nil, string, json.RawMessage:
+		return e
+	}
+
+	if _, err := json.Marshal(e.Payload); err != nil {
+		e.Severity = SeverityError
+		e.Payload = fmt.Sprintf("gaelog: failed to marshal payload of type %T: %v", e.Payload, err)
+	}
+	return e
+}
@@ -0,0 +1,29 @@
+package gaelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithPayloadCheck(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithPayloadCheck())
+
+	lg.Info(map[string]interface{}{"ch": make(chan int)})
+	lg.Info(map[string]interface{}{"ok": true})
+	lg.Info("text")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	msg, _ := entries[0]["message"].(string)
+	if entries[0]["severity"] != "ERROR" || !strings.Contains(msg, "map[string]interface {}") {
+		t.Errorf("Expected an error entry naming the payload's type, got %v", entries[0])
+	}
+	for _, e := range entries[1:] {
+		if e["severity"] != "INFO" {
+			t.Errorf("Expected a valid payload to be sent as is, got %v", e)
+		}
+	}
+}
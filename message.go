@@ -0,0 +1,39 @@
+package gaelog
+
+import (
+	"fmt"
+)
+
+// WithMessageField makes the Logger give object payloads that lack a "message" field one, so that
+// the Logs Explorer shows a readable summary line for them rather than collapsed JSON. The message
+// is the value of the payload's field with the given name, formatted as by fmt.Sprint, if it has
+// one; otherwise, if the payload implements fmt.Stringer, it is the result of its String method.
+// If field is empty then only String is used. Payloads that have neither are logged unchanged, as
+// are string payloads.
+func WithMessageField(field string) Option {
+	return func(cfg *config) {
+		cfg.synthesizeMessage = true
+		cfg.messageField = field
+	}
+}
+
+// withMessage returns payload with a "message" field synthesized as described by WithMessageField.
+func withMessage(payload interface{}, field string) interface{} {
+	fields, err := payloadFields(payload)
+	if err != nil || fields == nil {
+		return payload
+	}
+	if _, ok := fields["message"]; ok {
+		return payload
+	}
+
+	if v, ok := fields[field]; ok && field != "" {
+		fields["message"] = fmt.Sprint(v)
+		return fields
+	}
+	if s, ok := payload.(fmt.Stringer); ok {
+		fields["message"] = s.String()
+		return fields
+	}
+	return payload
+}
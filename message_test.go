@@ -0,0 +1,59 @@
+package gaelog
+
+import (
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+type stringerOrder struct {
+	ID string `json:"id"`
+}
+
+func (o stringerOrder) String() string {
+	return "order " + o.ID
+}
+
+func TestWithMessage(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload interface{}
+		field   string
+		want    interface{}
+	}{
+		{"field", order{ID: "a1", Total: 3}, "id", map[string]interface{}{"id": "a1", "total": float64(3), "message": "a1"}},
+		{"number_field", order{ID: "a1", Total: 3}, "total", map[string]interface{}{"id": "a1", "total": float64(3), "message": "3"}},
+		{"missing_field", order{ID: "a1"}, "name", order{ID: "a1"}},
+		{"stringer", stringerOrder{ID: "a1"}, "", map[string]interface{}{"id": "a1", "message": "order a1"}},
+		{"field_before_stringer", stringerOrder{ID: "a1"}, "id", map[string]interface{}{"id": "a1", "message": "a1"}},
+		{"has_message", map[string]interface{}{"message": "hi", "id": "a1"}, "id", map[string]interface{}{"message": "hi", "id": "a1"}},
+		{"string", "hi", "id", "hi"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if diff := pretty.Compare(withMessage(c.payload, c.field), c.want); diff != "" {
+				t.Errorf("Unexpected payload (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithMessageField(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMessageField("id"))
+	lg.Info(order{ID: "a1", Total: 3})
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	if payload["message"] != "a1" || payload["id"] != "a1" {
+		t.Errorf("Expected a message synthesized from the id field, got %v", entries[0]["message"])
+	}
+}
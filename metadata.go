@@ -0,0 +1,71 @@
+package gaelog
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+// WithMetadataClient sets the HTTP client used to query the metadata server, which is done on
+// Cloud Run to find the project ID, by WithProjectNumberLabel to find the project number, and by
+// WithInstanceIDLabel to find the instance ID. By default the metadata package's client is used.
+//
+// Each value is fetched once per process, by the first Logger that needs it, so this option is
+// best given to Configure.
+func WithMetadataClient(c *http.Client) Option {
+	return func(cfg *config) {
+		cfg.metadataClient = c
+	}
+}
+
+// WithMetadataTimeout bounds how long a Logger waits for the metadata server (see
+// WithMetadataClient). Off GCP there is no metadata server, and the metadata package retries for
+// several seconds before giving up, so a short timeout makes detection fail fast when running
+// locally. A lookup that times out keeps running in the background, and Loggers created once it
+// completes use its result. By default there is no timeout.
+func WithMetadataTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.metadataTimeout = d
+	}
+}
+
+// metadataLookup memoizes a value fetched from the metadata server.
+type metadataLookup struct {
+	once sync.Once
+
+	// done is closed once value and err are set.
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// get returns the value fetched by fetch, starting to fetch it using cfg's metadata client if
+// this is the first call, and waiting no longer than cfg's metadata timeout.
+func (l *metadataLookup) get(cfg *config, fetch func(*metadata.Client) (string, error)) (string, error) {
+	l.once.Do(func() {
+		l.done = make(chan struct{})
+		client := metadata.NewClient(cfg.metadataClient)
+		go func() {
+			defer close(l.done)
+			l.value, l.err = fetch(client)
+		}()
+	})
+
+	if cfg.metadataTimeout <= 0 {
+		<-l.done
+		return l.value, l.err
+	}
+
+	timer := time.NewTimer(cfg.metadataTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-l.done:
+		return l.value, l.err
+	case <-timer.C:
+		return "", fmt.Errorf("gaelog: timed out after %v waiting for the metadata server", cfg.metadataTimeout)
+	}
+}
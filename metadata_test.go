@@ -0,0 +1,79 @@
+package gaelog
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+// countingTransport is an http.RoundTripper that counts requests.
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithMetadataClient(t *testing.T) {
+	transport := &countingTransport{}
+	cfg := newConfig([]Option{WithMetadataClient(&http.Client{Transport: transport})})
+
+	var l metadataLookup
+	got, err := l.get(cfg, func(c *metadata.Client) (string, error) {
+		return c.Get("project/project-id")
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != testProjectIDMetadataServer {
+		t.Errorf("Expected %q, got %q", testProjectIDMetadataServer, got)
+	}
+	if transport.requests != 1 {
+		t.Errorf("Expected the given client to make 1 request, got %d", transport.requests)
+	}
+
+	// The value is memoized.
+	if _, err := l.get(cfg, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transport.requests != 1 {
+		t.Errorf("Expected the value to be memoized, got %d requests", transport.requests)
+	}
+}
+
+func TestWithMetadataTimeout(t *testing.T) {
+	cfg := newConfig([]Option{WithMetadataTimeout(10 * time.Millisecond)})
+
+	release := make(chan struct{})
+	var l metadataLookup
+	_, err := l.get(cfg, func(*metadata.Client) (string, error) {
+		<-release
+		return "slow-project", nil
+	})
+	if err == nil {
+		t.Fatal("Expected a timeout error")
+	}
+
+	// The lookup keeps running, and its result is used once it completes.
+	close(release)
+	<-l.done
+	got, err := l.get(cfg, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "slow-project" {
+		t.Errorf("Expected %q, got %q", "slow-project", got)
+	}
+}
+
+func TestWithInstanceIDLabel(t *testing.T) {
+	lg, _ := newRedirectedLogger(t, WithInstanceIDLabel())
+
+	if got := lg.entry(SeverityInfo, "x").Labels[InstanceIDLabel]; got != testInstanceID {
+		t.Errorf("Expected label %s to be %q, got %q", InstanceIDLabel, testInstanceID, got)
+	}
+}
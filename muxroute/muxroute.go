@@ -0,0 +1,28 @@
+// Package muxroute adapts the gorilla/mux router for use with gaelog.WithRouteLabel.
+package muxroute
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Pattern returns the path template of the gorilla/mux route that matched r, such as
+// "/users/{id}", or the empty string if r was not routed by gorilla/mux. It is a
+// gaelog.RouteFunc:
+//
+//	r := mux.NewRouter()
+//	r.Use(func(h http.Handler) http.Handler {
+//		return gaelog.WrapWithOptions(h, gaelog.WithRouteLabel(muxroute.Pattern))
+//	})
+func Pattern(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return tmpl
+}
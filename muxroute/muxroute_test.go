@@ -0,0 +1,26 @@
+package muxroute
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPattern(t *testing.T) {
+	var got string
+	r := mux.NewRouter()
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		got = Pattern(r)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	if got != "/users/{id}" {
+		t.Errorf("Expected %q, got %q", "/users/{id}", got)
+	}
+
+	if got := Pattern(httptest.NewRequest("GET", "/users/42", nil)); got != "" {
+		t.Errorf("Expected no pattern for an unrouted request, got %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package gaelog
+
+import (
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// Named returns a Logger that logs to the log with the given ID rather than the Logger's own, for
+// keeping separate streams of entries, such as business events, apart from an app's other logs.
+// Its entries are correlated with the same request and carry the same labels.
+//
+// If resource is not nil then it is set as the monitored resource of the returned Logger's
+// entries in place of the one detected for the app; for example, a resource of type "global" may
+// suit entries that aren't about the app itself. If it is nil then the Logger's resource is used.
+//
+// The returned Logger shares the Logger's Stackdriver Logging client, so it must not be used
+// after the Logger is closed. Closing it flushes its entries but does not close the client.
+// Closing the Logger closes every named Logger it created that is still open, so that their
+// entries are flushed before the client is closed. A Logger created with WithEncoder writes every
+// entry to stdout regardless of log ID, so only the resource is overridden.
+func (lg *Logger) Named(logID string, resource *monitoredres.MonitoredResource) *Logger {
+	n := lg.newNamed(logID, resource)
+	n.namedBy = lg
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	lg.addNamedView(n)
+	return n
+}
+
+// addNamedView records n as one of lg's open named Loggers. lg.mu must be held.
+func (lg *Logger) addNamedView(n *Logger) {
+	if lg.namedViews == nil {
+		lg.namedViews = make(map[*Logger]struct{})
+	}
+	lg.namedViews[n] = struct{}{}
+}
+
+// forgetNamed removes n, which has been closed, from lg's open named Loggers so that a long-lived
+// Logger doesn't hold on to every Logger it has made with Named.
+func (lg *Logger) forgetNamed(n *Logger) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	delete(lg.namedViews, n)
+}
+
+// newNamed returns a new Logger for the log with the given ID, as described in Named, without
+// recording it on lg.
+func (lg *Logger) newNamed(logID string, resource *monitoredres.MonitoredResource) *Logger {
+	n := lg.derive()
+	if resource != nil {
+		n.monRes = resource
+	}
+
+	cfg := lg.config()
+	logID = cfg.serviceLogID(lg.service, logID)
+
+	switch {
+	case lg.logger == nil:
+		// The Logger has fallen back, so the named Logger does too.
+	case lg.client != nil:
+		n.logger = cfg.clientLogger(lg.client, logID)
+	case lg.lazy != nil:
+		n.logger = lg.lazy.named(logID, cfg)
+	default:
+		n.logger = lg.unsampled()
+	}
+
+	return n
+}
+
+// LogToName logs v with the given severity to the log with the given ID rather than the Logger's
+// own, for one-off entries such as those written to a log shared by several apps. It is like
+// calling Log on the Logger returned by Named(logID, nil), except that the named Logger is created
+// on first use and reused by later calls with the same log ID.
+func (lg *Logger) LogToName(logID string, severity Severity, v interface{}) {
+	lg.namedLogger(logID).Log(severity, v)
+}
+
+// namedLogger returns the Logger for the log with the given ID used by LogToName, creating it if
+// needed.
+func (lg *Logger) namedLogger(logID string) *Logger {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	n, ok := lg.named[logID]
+	if !ok {
+		n = lg.newNamed(logID, nil)
+		n.namedBy = lg
+		if lg.named == nil {
+			lg.named = make(map[string]*Logger)
+		}
+		lg.named[logID] = n
+		lg.addNamedView(n)
+	}
+	return n
+}
+
+// closeNamed closes the named Loggers created by Named and LogToName, returning the first error.
+func (lg *Logger) closeNamed() error {
+	lg.mu.Lock()
+	views := lg.namedViews
+	lg.namedViews = nil
+	lg.mu.Unlock()
+
+	var firstErr error
+	for n := range views {
+		if err := n.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
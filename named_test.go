@@ -0,0 +1,181 @@
+package gaelog
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+func TestNamed(t *testing.T) {
+	global := &monitoredres.MonitoredResource{Type: "global"}
+
+	for _, c := range []struct {
+		name    string
+		options []Option
+	}{
+		{"client", nil},
+		{"lazy_client", []Option{WithLazyClient()}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			lg, buf := newRedirectedLogger(t, c.options...)
+
+			events := lg.Named("business_events", global)
+			if events.IsFallback() {
+				t.Fatal("Expected the named Logger not to be in fallback mode")
+			}
+			if e := events.entry(SeverityInfo, "x"); e.Resource != global {
+				t.Errorf("Expected the overridden resource, got %v", e.Resource)
+			}
+			if e := lg.Named("other", nil).entry(SeverityInfo, "x"); e.Resource != lg.monRes {
+				t.Errorf("Expected the Logger's resource, got %v", e.Resource)
+			}
+
+			events.Info("order placed")
+			if err := events.Close(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			entries := decodeEntries(t, buf)
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+			if entries[0]["message"] != "order placed" || entries[0]["logging.googleapis.com/trace"] != lg.trace {
+				t.Errorf("Unexpected entry: %v", entries[0])
+			}
+		})
+	}
+}
+
+func TestNamedFallback(t *testing.T) {
+	lg := &Logger{}
+	if !lg.Named("business_events", nil).IsFallback() {
+		t.Error("Expected the named Logger of a fallback Logger to be in fallback mode")
+	}
+}
+
+func TestLogToName(t *testing.T) {
+	f, addr := startFakeEmulator(t)
+	setGAEEnvVars(t)
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEmulatorHost(addr))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lg.LogToName("deployments", SeverityNotice, "deployed v2")
+	lg.LogToName("deployments", SeverityNotice, "deployed v3")
+	if len(lg.named) != 1 {
+		t.Errorf("Expected the named Logger to be reused, got %d named Loggers", len(lg.named))
+	}
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var found int
+	for _, e := range f.entries {
+		if msg := e.GetTextPayload(); msg != "deployed v2" && msg != "deployed v3" {
+			continue
+		}
+		found++
+
+		if want := "projects/" + testProjectID + "/logs/deployments"; e.LogName != want {
+			t.Errorf("Expected log name %q, got %q", want, e.LogName)
+		}
+		if e.GetSeverity().String() != "NOTICE" {
+			t.Errorf("Expected severity NOTICE, got %v", e.GetSeverity())
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected 2 entries to be written to the emulator, got %v", f.entries)
+	}
+}
+
+func TestLogToNameFallback(t *testing.T) {
+	lg := &Logger{}
+	lg.LogToName("deployments", SeverityNotice, "deployed v2")
+	if n := lg.named["deployments"]; n == nil || !n.IsFallback() {
+		t.Error("Expected the named Logger of a fallback Logger to be in fallback mode")
+	}
+}
+
+func TestCloseFlushesNamed(t *testing.T) {
+	f, addr := startFakeEmulator(t)
+
+	unset := setEnvVars(map[string]string{
+		"K_SERVICE":       testServiceID,
+		"K_REVISION":      testVersionID,
+		"K_CONFIGURATION": testConfigurationName,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	lg, err := NewWithOptions(r, WithEmulatorHost(addr), WithProjectID("emulated-project"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lg.Named("business_events", nil).Info("order placed")
+	lg.LogToName("deployments", SeverityNotice, "deployed v2")
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	logNames := make(map[string]string)
+	for _, e := range f.entries {
+		logNames[e.GetTextPayload()] = e.LogName
+	}
+	for msg, logID := range map[string]string{"order placed": "business_events", "deployed v2": "deployments"} {
+		if want := "projects/emulated-project/logs/" + logID; logNames[msg] != want {
+			t.Errorf("Expected %q to be written to %q, got %q", msg, want, logNames[msg])
+		}
+	}
+}
+
+func TestCloseClosesNamed(t *testing.T) {
+	cl := &countingLogger{}
+	lg := &Logger{cfg: defaultConfig, logger: cl}
+
+	events := lg.Named("business_events", nil)
+	lg.LogToName("deployments", SeverityNotice, "deployed v2")
+	lg.LogToName("deployments", SeverityNotice, "deployed v3")
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Each named Logger is flushed once, as is the Logger itself.
+	if cl.flushes != 3 {
+		t.Errorf("Expected 3 flushes, got %d", cl.flushes)
+	}
+
+	// The named Loggers are already closed, so closing them again does nothing.
+	events.Close()
+	if cl.flushes != 3 {
+		t.Errorf("Expected closing a named Logger again not to flush, got %d flushes", cl.flushes)
+	}
+}
+
+func TestNamedForgottenOnClose(t *testing.T) {
+	lg := &Logger{cfg: defaultConfig, logger: &countingLogger{}}
+
+	for i := 0; i < 3; i++ {
+		lg.Named("business_events", nil).Close()
+	}
+	open := lg.Named("business_events", nil)
+
+	if len(lg.namedViews) != 1 {
+		t.Errorf("Expected only the open named Logger to be kept, got %d", len(lg.namedViews))
+	}
+	if _, ok := lg.namedViews[open]; !ok {
+		t.Errorf("Expected the open named Logger to be kept")
+	}
+}
@@ -0,0 +1,36 @@
+package gaelog
+
+import (
+	"context"
+	"sync"
+)
+
+// seenOnceKeys holds the keys passed to LogOnce.
+var seenOnceKeys sync.Map
+
+// firstOnce reports whether this is the first time key has been seen in the process.
+func firstOnce(key string) bool {
+	_, seen := seenOnceKeys.LoadOrStore(key, struct{}{})
+	if seen {
+		countDrop(dropDeduped, 1)
+	}
+	return !seen
+}
+
+// LogOnce is like Log but logs only the first time it is called with the given key in the
+// process, for conditions that recur on every request but are worth logging only once, such as
+// deprecation notices and configuration warnings. Calls with the same key from any Logger count,
+// so keys should be specific to the condition, e.g. "deprecated-endpoint:/v1/items".
+func (lg *Logger) LogOnce(key string, severity Severity, v interface{}) {
+	if firstOnce(key) {
+		lg.Log(severity, v)
+	}
+}
+
+// LogOnce is like Log but logs only the first time it is called with the given key in the
+// process. See Logger.LogOnce.
+func LogOnce(ctx context.Context, key string, severity Severity, v interface{}) {
+	if firstOnce(key) {
+		Log(ctx, severity, v)
+	}
+}
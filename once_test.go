@@ -0,0 +1,34 @@
+package gaelog
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestLogOnce(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			LogOnce(ctx, "test-log-once-a", SeverityWarning, "a")
+		}()
+	}
+	wg.Wait()
+
+	lg.LogOnce("test-log-once-b", SeverityWarning, "b")
+	lg.LogOnce("test-log-once-a", SeverityWarning, "a again")
+	lg.LogOnce("test-log-once-b", SeverityWarning, "b again")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0]["message"] != "a" || entries[1]["message"] != "b" {
+		t.Errorf("Expected entries a and b, got %v", entries)
+	}
+}
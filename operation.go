@@ -0,0 +1,81 @@
+package gaelog
+
+import (
+	"context"
+
+	"cloud.google.com/go/logging"
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// OperationField is the key of the field of an object payload that sets the entry's operation, as
+// in structured logs written to stdout, which the logging agents on Google Cloud interpret the same
+// way. Its value is an object with the fields "id" and "producer", which identify the operation,
+// and "first" and "last", which mark the entry as its first or last:
+//
+//	lg.Info(map[string]interface{}{
+//		"message": "export started",
+//		gaelog.OperationField: map[string]interface{}{
+//			"id":       exportID,
+//			"producer": "example.com/export",
+//			"first":    true,
+//		},
+//	})
+//
+// The field is removed from the payload and set as the entry's Operation, taking precedence over
+// any operation set with WithOperation. A *logpb.LogEntryOperation may be given as the value too.
+const OperationField = "logging.googleapis.com/operation"
+
+// WithOperation returns a copy of ctx with which the package-level logging functions (Logf, Log,
+// and so on) log entries as part of the operation with the given ID and producer, so that they are
+// grouped together in the Logs Explorer. It is shorthand for WithEntryDefaults with the entry's
+// Operation set. To mark an entry as the first or last of the operation, set OperationField in its
+// payload.
+func WithOperation(ctx context.Context, id, producer string) context.Context {
+	return WithEntryDefaults(ctx, logging.Entry{
+		Operation: &logpb.LogEntryOperation{Id: id, Producer: producer},
+	})
+}
+
+// liftOperation moves the operation given by OperationField in e's payload, if any, to the
+// entry's Operation. The payload is copied rather than modified.
+func liftOperation(e *logging.Entry) {
+	fields, ok := e.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	v, ok := fields[OperationField]
+	if !ok {
+		return
+	}
+
+	op := parseOperation(v)
+	if op == nil {
+		return
+	}
+
+	payload := make(map[string]interface{}, len(fields)-1)
+	for k, v := range fields {
+		if k != OperationField {
+			payload[k] = v
+		}
+	}
+	e.Payload = payload
+	e.Operation = op
+}
+
+// parseOperation returns the operation described by the value of OperationField, or nil if v is
+// not of a supported type.
+func parseOperation(v interface{}) *logpb.LogEntryOperation {
+	switch x := v.(type) {
+	case *logpb.LogEntryOperation:
+		return x
+	case map[string]interface{}:
+		op := &logpb.LogEntryOperation{}
+		op.Id, _ = x["id"].(string)
+		op.Producer, _ = x["producer"].(string)
+		op.First, _ = x["first"].(bool)
+		op.Last, _ = x["last"].(bool)
+		return op
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package gaelog
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+func TestOperationField(t *testing.T) {
+	rec := &entryRecorder{}
+	lg := &Logger{cfg: defaultConfig, logger: sinkLogger{rec}}
+
+	payload := map[string]interface{}{
+		"message": "export started",
+		OperationField: map[string]interface{}{
+			"id":       "export-1",
+			"producer": "example.com/export",
+			"first":    true,
+		},
+	}
+	lg.Info(payload)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	e := rec.entries[0]
+	if op := e.Operation; op == nil || op.Id != "export-1" || op.Producer != "example.com/export" || !op.First || op.Last {
+		t.Errorf("Unexpected operation: %v", op)
+	}
+	if want := map[string]interface{}{"message": "export started"}; !reflect.DeepEqual(e.Payload, want) {
+		t.Errorf("Expected payload %v, got %v", want, e.Payload)
+	}
+	if _, ok := payload[OperationField]; !ok {
+		t.Errorf("Expected the caller's payload not to be modified")
+	}
+
+	// The entry encodes back to the same field.
+	b, err := EncodeEntry(e)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]interface{}{"id": "export-1", "producer": "example.com/export", "first": true, "last": false}
+	if !reflect.DeepEqual(m[OperationField], want) {
+		t.Errorf("Expected encoded operation %v, got %v", want, m[OperationField])
+	}
+}
+
+func TestWithOperation(t *testing.T) {
+	rec := &entryRecorder{}
+	lg := &Logger{cfg: defaultConfig, logger: sinkLogger{rec}}
+	ctx := WithOperation(context.WithValue(context.Background(), ctxKey, lg), "export-1", "example.com/export")
+
+	Info(ctx, "exporting")
+	Info(ctx, map[string]interface{}{
+		"message":      "export done",
+		OperationField: &logpb.LogEntryOperation{Id: "export-1", Producer: "example.com/export", Last: true},
+	})
+
+	if len(rec.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(rec.entries))
+	}
+	if op := rec.entries[0].Operation; op == nil || op.Id != "export-1" || op.Last {
+		t.Errorf("Unexpected operation of first entry: %v", op)
+	}
+	if op := rec.entries[1].Operation; op == nil || op.Id != "export-1" || !op.Last {
+		t.Errorf("Unexpected operation of last entry: %v", op)
+	}
+}
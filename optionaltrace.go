@@ -0,0 +1,14 @@
+package gaelog
+
+// WithOptionalTrace makes the Logger of a request without a trace context, such as an internal
+// health check or a cron job, log to Stackdriver Logging with the right resource as usual, just
+// without correlating its entries with a trace, rather than falling back to the standard library's
+// log package. Such a Logger has no span either, even with WithSpans.
+//
+// Which untraced requests are sampled by WithErrorSampling and WithSeveritySampling can't depend
+// on their trace IDs, so they are all treated alike.
+func WithOptionalTrace() Option {
+	return func(cfg *config) {
+		cfg.optionalTrace = true
+	}
+}
@@ -0,0 +1,32 @@
+package gaelog
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithOptionalTrace(t *testing.T) {
+	setGAEEnvVars(t)
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+
+	if lg, err := NewWithOptions(r, WithSink(nopLogger{})); err == nil || !lg.IsFallback() {
+		t.Fatal("Expected a Logger without a trace to fall back by default")
+	}
+
+	lg, err := NewWithOptions(r, WithSink(nopLogger{}), WithOptionalTrace(), WithSpans())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer lg.Close()
+	if lg.IsFallback() {
+		t.Fatal("Expected a Logger without a trace not to fall back")
+	}
+
+	e := lg.entry(SeverityInfo, "untraced")
+	if e.Trace != "" || e.SpanID != "" {
+		t.Errorf("Expected no trace or span, got %q and %q", e.Trace, e.SpanID)
+	}
+	if e.Resource == nil || e.Resource.Type != GAEAppResourceType {
+		t.Errorf("Expected the app's resource, got %v", e.Resource)
+	}
+}
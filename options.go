@@ -0,0 +1,292 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// An Option configures a Logger. Options are passed to NewWithOptions and WrapWithOptions.
+type Option func(*config)
+
+// config holds the settings assembled from a set of Options.
+type config struct {
+	logID          string
+	servicePrefix  bool
+	loggerOptions  []logging.LoggerOption
+	clientContext  context.Context
+	clientOptions  []option.ClientOption
+	labels         map[string]string
+	contextFields  []contextField
+	projectID      string
+	logProjectID   string
+	resource       *monitoredres.MonitoredResource
+	detectionOrder []string
+	emulatorHost   string
+
+	metadataClient  *http.Client
+	metadataTimeout time.Duration
+
+	partialSuccess bool
+	healthCheck    bool
+	onError        func(error)
+
+	projectNumberLabel bool
+	instanceIDLabel    bool
+	goroutineLabel     bool
+	lazyClient         bool
+	encoder            Encoder
+	sink               Sink
+	splitOutput        *splitOutput
+	output             io.Writer
+	testTrace          *testTrace
+
+	spans       bool
+	spanWriter  SpanWriter
+	idGenerator IDGenerator
+
+	slowRequestThreshold time.Duration
+	summaryFormat        SummaryFormat
+	latencyBreakdown     bool
+	sampledSummaryOnly   bool
+	summaryFraction      float64
+	summarySeverity      Severity
+	statusSeverity       func(status int) Severity
+	loggedHeaders        []string
+	recoverPanics        bool
+	stdLogBridge         bool
+	traceResponseHeader  string
+	correlationHeader    string
+
+	route         RouteFunc
+	propagators   []TracePropagator
+	optionalTrace bool
+
+	minSeverity     Severity
+	defaultSeverity Severity
+	exitOnSeverity  *exitOnSeverity
+	maxPayloadSize  int
+	maxEntries      int
+	rateLimit       *rateLimiter
+	requestTail     int
+	checkPayloads   bool
+	bytesAsText     bool
+	strictFormat    bool
+
+	timestampPrecision time.Duration
+
+	sampling         *errorSampling
+	severitySampling map[Severity]float64
+
+	synthesizeMessage bool
+	messageField      string
+
+	syncAll      bool
+	syncWrites   bool
+	syncSeverity Severity
+	writeTimeout time.Duration
+}
+
+// globalOptions are the Options set with Configure. They are applied before those given to each
+// Logger.
+var globalOptions []Option
+
+// defaultConfig is the config used when no Options are given.
+var defaultConfig = newConfig(nil)
+
+func newConfig(options []Option) *config {
+	cfg := &config{
+		logID:           DefaultLogID,
+		clientContext:   context.Background(),
+		emulatorHost:    os.Getenv(EmulatorHostEnvVar),
+		summarySeverity: SeverityInfo,
+
+		severitySampling: severitySamplingFromEnv(),
+	}
+	for _, opt := range globalOptions {
+		opt(cfg)
+	}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Configure sets package-wide default Options, such as the log ID, labels, or encoder, for apps
+// that would otherwise pass the same Options to every call to NewWithOptions, WrapWithOptions,
+// and so on. Options given to those functions are applied after the defaults and so take
+// precedence over them. The defaults also apply to New, NewWithID, Wrap, and WrapWithID.
+//
+// Each call replaces the defaults set by the previous one, so calling Configure more than once
+// with the same Options has the same effect as calling it once, and calling it with no Options
+// clears the defaults. Configure should be called during initialization, before any Loggers are
+// created or handlers are wrapped; handlers already wrapped keep the defaults in effect when they
+// were wrapped. It must not be called concurrently with the creation of Loggers.
+func Configure(options ...Option) {
+	globalOptions = append([]Option(nil), options...)
+	defaultConfig = newConfig(nil)
+}
+
+// wrapsResponseWriter reports whether a wrapped handler needs to observe the response.
+func (cfg *config) wrapsResponseWriter() bool {
+	return cfg.slowRequestThreshold > 0 || cfg.summaryFormat != 0 || cfg.recoverPanics || cfg.spanWriter != nil
+}
+
+// WithLogID sets the log ID of the underlying Stackdriver Logging logger. If this option is not
+// given then DefaultLogID is used.
+func WithLogID(logID string) Option {
+	return func(cfg *config) {
+		cfg.logID = logID
+	}
+}
+
+// WithServicePrefixedLogID prefixes the log ID of the underlying Stackdriver Logging logger with
+// the name of the service, as given by GAE_SERVICE or K_SERVICE, so that the log ID becomes
+// "<service>.<logID>". The log IDs given to Named and LogToName are prefixed too. When several
+// services in one project log with the same log IDs this keeps each one's logs under distinct
+// log names.
+func WithServicePrefixedLogID() Option {
+	return func(cfg *config) {
+		cfg.servicePrefix = true
+	}
+}
+
+// serviceLogID returns the log ID with which a Logger for the given service logs to the log with
+// the given ID, prefixed with the service as WithServicePrefixedLogID calls for.
+func (cfg *config) serviceLogID(service, logID string) string {
+	if cfg.servicePrefix && service != "" {
+		return service + "." + logID
+	}
+	return logID
+}
+
+// WithClientContext sets the context used to create the underlying Stackdriver Logging client. By
+// default context.Background() is used. The request's context is not used because it is canceled
+// when the request completes, which should not affect the client; any context given should likewise
+// be long-lived.
+func WithClientContext(ctx context.Context) Option {
+	return func(cfg *config) {
+		cfg.clientContext = ctx
+	}
+}
+
+// WithProjectID sets the ID of the project that entries are logged to and that traces are
+// attributed to, rather than that of the environment. To write entries to another project without
+// changing how they are attributed, see WithLogProjectID. On Cloud Run this also avoids fetching the
+// project ID from the metadata server, which is useful when running elsewhere, such as against an
+// emulator (see WithEmulatorHost). The environment variables described in NewWithID must still
+// be set.
+func WithProjectID(projectID string) Option {
+	return func(cfg *config) {
+		cfg.projectID = projectID
+	}
+}
+
+// WithLogProjectID sets the ID of the project that entries are written to, for setups in which
+// one project collects the logs of apps in others. Unlike WithProjectID it leaves entries
+// otherwise as they would be: their monitored resource is still labeled with the app's project ID
+// and their traces are still attributed to the app's project, which is where App Engine and Cloud
+// Run record them. If this option is not given then entries are written to the app's project, as
+// detected or set with WithProjectID.
+func WithLogProjectID(projectID string) Option {
+	return func(cfg *config) {
+		cfg.logProjectID = projectID
+	}
+}
+
+// WithResource sets the monitored resource of the Logger's entries in place of the one detected
+// for the app. The environment variables described in NewWithID must still be set.
+func WithResource(resource *monitoredres.MonitoredResource) Option {
+	return func(cfg *config) {
+		cfg.resource = resource
+	}
+}
+
+// parent returns the parent that a Logger for the app described by info writes entries to.
+func (cfg *config) parent(info serviceInfo) string {
+	projectID := info.projectID
+	if cfg.logProjectID != "" {
+		projectID = cfg.logProjectID
+	}
+	return fmt.Sprintf("projects/%s", projectID)
+}
+
+// WithLabels sets labels on every entry logged by the Logger. It may be given more than once, in
+// which case the labels are merged, with later values taking precedence.
+func WithLabels(labels map[string]string) Option {
+	return func(cfg *config) {
+		cfg.labels = mergeLabels(cfg.labels, labels)
+	}
+}
+
+// WithProjectNumberLabel sets a label with key ProjectNumberLabel on every entry logged by the
+// Logger. Its value is the numeric project ID as returned by NumericProjectID. If the numeric
+// project ID cannot be fetched then the label is omitted.
+func WithProjectNumberLabel() Option {
+	return func(cfg *config) {
+		cfg.projectNumberLabel = true
+	}
+}
+
+// WithInstanceIDLabel sets a label with key InstanceIDLabel on every entry logged by the Logger,
+// for telling which instance handled a request. Its value is the ID of the instance the app is
+// running on, which on Cloud Run is given only by the metadata server, not by an environment
+// variable. It is fetched once per process. If it cannot be fetched then the label is omitted.
+func WithInstanceIDLabel() Option {
+	return func(cfg *config) {
+		cfg.instanceIDLabel = true
+	}
+}
+
+// WithLoggerOptions passes the given options through to the underlying Stackdriver Logging logger.
+// See NewWithID for caveats.
+func WithLoggerOptions(options ...logging.LoggerOption) Option {
+	return func(cfg *config) {
+		cfg.loggerOptions = append(cfg.loggerOptions, options...)
+	}
+}
+
+// WithWriteConcurrency sets the number of goroutines with which the underlying Stackdriver Logging
+// logger sends entries, as the ConcurrentWriteLimit LoggerOption does. The default is 1, which
+// suits small instances, such as Cloud Run instances with a fraction of a CPU; raise it only if
+// entries are logged faster than one goroutine can send them. It has no effect on Loggers that
+// don't send entries to Stackdriver Logging, such as those created with WithEncoder.
+func WithWriteConcurrency(n int) Option {
+	return WithLoggerOptions(logging.ConcurrentWriteLimit(n))
+}
+
+// WithBufferedByteLimit sets the number of bytes of entries that the underlying Stackdriver Logging
+// logger keeps in memory while waiting to send them, as the BufferedByteLimit LoggerOption does.
+// Entries logged while the limit is reached are dropped, and logging.ErrOverflow is passed to the
+// error handler (see WithErrorHandler). The default, logging.DefaultBufferedByteLimit, is 1 GiB,
+// which is more than the memory of a small instance; on a Cloud Run instance with 512 MiB, say,
+// a limit of 32 MiB or so keeps a backlog of entries from exhausting memory. The limit applies to
+// each underlying logger, of which each Logger not created by a Handler has its own.
+func WithBufferedByteLimit(n int) Option {
+	return WithLoggerOptions(logging.BufferedByteLimit(n))
+}
+
+// mergeLabels returns a new map containing the labels in a and b, with values in b taking
+// precedence. Neither a nor b is modified because they may be shared by many Loggers.
+func mergeLabels(a, b map[string]string) map[string]string {
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// withLabel returns a copy of labels with key set to value. labels may be nil.
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	return mergeLabels(labels, map[string]string{key: value})
+}
@@ -0,0 +1,93 @@
+package gaelog
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/logging"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestNewConfig(t *testing.T) {
+	cfg := newConfig(nil)
+	if cfg.logID != DefaultLogID {
+		t.Errorf("Expected log ID %q, got %q", DefaultLogID, cfg.logID)
+	}
+
+	cfg = newConfig([]Option{WithLogID("my_log")})
+	if cfg.logID != "my_log" {
+		t.Errorf("Expected log ID %q, got %q", "my_log", cfg.logID)
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	first := map[string]string{"a": "1", "b": "2"}
+	cfg := newConfig([]Option{
+		WithLabels(first),
+		WithLabels(map[string]string{"b": "3", "c": "4"}),
+	})
+
+	want := map[string]string{"a": "1", "b": "3", "c": "4"}
+	if diff := pretty.Compare(cfg.labels, want); diff != "" {
+		t.Errorf("Unexpected result (-got +want):\n%s", diff)
+	}
+
+	if first["b"] != "2" {
+		t.Errorf("Expected the given labels to be unmodified, got %v", first)
+	}
+}
+
+func TestWithClientContext(t *testing.T) {
+	if newConfig(nil).clientContext != context.Background() {
+		t.Errorf("Expected the default client context to be context.Background()")
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	if got := newConfig([]Option{WithClientContext(ctx)}).clientContext; got != ctx {
+		t.Errorf("Expected the given client context, got %v", got)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	Configure(WithLogID("global_log"), WithLabels(map[string]string{"a": "global", "b": "global"}))
+	defer Configure()
+
+	cfg := newConfig(nil)
+	if cfg.logID != "global_log" {
+		t.Errorf("Expected log ID %q, got %q", "global_log", cfg.logID)
+	}
+	if defaultConfig.logID != "global_log" {
+		t.Errorf("Expected the default config to use log ID %q, got %q", "global_log", defaultConfig.logID)
+	}
+
+	cfg = newConfig([]Option{WithLogID("my_log"), WithLabels(map[string]string{"b": "mine"})})
+	if cfg.logID != "my_log" {
+		t.Errorf("Expected log ID %q, got %q", "my_log", cfg.logID)
+	}
+	want := map[string]string{"a": "global", "b": "mine"}
+	if diff := pretty.Compare(cfg.labels, want); diff != "" {
+		t.Errorf("Unexpected result (-got +want):\n%s", diff)
+	}
+
+	// Configuring again replaces rather than adds to the defaults.
+	Configure(WithLogID("global_log"))
+	if labels := newConfig(nil).labels; labels != nil {
+		t.Errorf("Expected no labels, got %v", labels)
+	}
+
+	Configure()
+	if cfg := newConfig(nil); cfg.logID != DefaultLogID {
+		t.Errorf("Expected log ID %q, got %q", DefaultLogID, cfg.logID)
+	}
+}
+
+func TestWithWriteLimits(t *testing.T) {
+	cfg := newConfig([]Option{WithWriteConcurrency(2), WithBufferedByteLimit(32 << 20)})
+
+	want := []logging.LoggerOption{logging.ConcurrentWriteLimit(2), logging.BufferedByteLimit(32 << 20)}
+	if !reflect.DeepEqual(cfg.loggerOptions, want) {
+		t.Errorf("Expected logger options %v, got %v", want, cfg.loggerOptions)
+	}
+}
@@ -0,0 +1,127 @@
+package gaelog
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// WithRecoverPanics makes a wrapped handler recover panics. The panic is logged at critical
+// severity in the format recognized by Error Reporting, and if the handler hadn't yet written a
+// response then a 500 Internal Server Error is written. As with net/http's own recovery, a panic
+// with the value http.ErrAbortHandler is not logged and is panicked again so that the server
+// aborts the response. It has no effect on Loggers created with New and its variants.
+//
+// The entry's payload has separate fields for the recovered value and the stack trace, so that
+// panics can be queried and grouped by the type of the value:
+//
+//	{
+//	  "message": "panic: runtime error: index out of range [3] with length 3",
+//	  "stack_trace": "goroutine 7 [running]:\n...",
+//	  "panic": {
+//	    "kind": "error",
+//	    "type": "runtime.boundsError",
+//	    "value": "runtime error: index out of range [3] with length 3"
+//	  },
+//	  ...
+//	}
+//
+// The kind is "error" if the value is an error, "string" if it is a string, and "other" otherwise.
+func WithRecoverPanics() Option {
+	return func(cfg *config) {
+		cfg.recoverPanics = true
+	}
+}
+
+// Kinds of recovered panic values.
+const (
+	panicKindError  = "error"
+	panicKindString = "string"
+	panicKindOther  = "other"
+)
+
+// panicEvent is the payload of an entry logged for a recovered panic.
+type panicEvent struct {
+	errorEvent
+	StackTrace string     `json:"stack_trace"`
+	Panic      panicValue `json:"panic"`
+
+	// Recent are the entries kept by WithRequestTail, if any.
+	Recent []recentEntry `json:"recent_entries,omitempty"`
+}
+
+// panicValue describes the value recovered from a panic.
+type panicValue struct {
+	Kind  string `json:"kind"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newPanicValue(v interface{}) panicValue {
+	switch v := v.(type) {
+	case error:
+		return panicValue{Kind: panicKindError, Type: fmt.Sprintf("%T", v), Value: v.Error()}
+	case string:
+		return panicValue{Kind: panicKindString, Type: "string", Value: v}
+	}
+	return panicValue{Kind: panicKindOther, Type: fmt.Sprintf("%T", v), Value: fmt.Sprint(v)}
+}
+
+// serveRecovering calls h, recovering and logging any panic. w must not be nil.
+func (lg *Logger) serveRecovering(h http.Handler, w *responseWriter, r *http.Request) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		if v == http.ErrAbortHandler {
+			panic(v)
+		}
+
+		lg.logPanic(v, debug.Stack(), r)
+		if w.status == 0 && !w.hijacked {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}()
+
+	h.ServeHTTP(w, r)
+}
+
+// logPanic logs the value recovered from a panic that occurred while handling r, and the stack
+// trace at the time it was recovered.
+func (lg *Logger) logPanic(v interface{}, stack []byte, r *http.Request) {
+	value := newPanicValue(v)
+	message := "panic: " + value.Value
+
+	if lg.logger == nil {
+		log.Printf("%s\n\n%s", message, stack)
+		return
+	}
+
+	event := panicEvent{
+		errorEvent: errorEvent{
+			Type:    reportedErrorEventType,
+			Message: message,
+			ServiceContext: errorServiceContext{
+				Service: lg.service,
+				Version: lg.version,
+			},
+			Context: &errorContext{
+				HTTPRequest: &errorHTTPRequest{
+					Method:             r.Method,
+					URL:                r.URL.String(),
+					UserAgent:          r.UserAgent(),
+					Referrer:           r.Referer(),
+					ResponseStatusCode: http.StatusInternalServerError,
+					RemoteIP:           remoteHost(r),
+				},
+				User: lg.currentUser(),
+			},
+		},
+		StackTrace: string(stack),
+		Panic:      value,
+		Recent:     lg.recentEntries(),
+	}
+	lg.write(lg.entry(SeverityCritical, event))
+}
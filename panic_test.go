@@ -0,0 +1,129 @@
+package gaelog
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	return "stringer"
+}
+
+func TestWithRecoverPanics(t *testing.T) {
+	cases := []struct {
+		name  string
+		value interface{}
+		want  panicValue
+	}{
+		{"error", errors.New("oh no"), panicValue{Kind: "error", Type: "*errors.errorString", Value: "oh no"}},
+		{"string", "oh no", panicValue{Kind: "string", Type: "string", Value: "oh no"}},
+		{"int", 42, panicValue{Kind: "other", Type: "int", Value: "42"}},
+		{"stringer", panicStringer{}, panicValue{Kind: "other", Type: "gaelog.panicStringer", Value: "stringer"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+
+			var buf bytes.Buffer
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic(c.value)
+			}), WithRecoverPanics(), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+			req := httptest.NewRequest("GET", "http://example.com/boom", nil)
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+			}
+
+			entries := decodeEntries(t, &buf)
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+
+			e := entries[0]
+			if e["severity"] != "CRITICAL" {
+				t.Errorf("Expected severity CRITICAL, got %v", e["severity"])
+			}
+
+			payload := e["message"].(map[string]interface{})
+			if payload["@type"] != reportedErrorEventType {
+				t.Errorf("Expected @type %q, got %v", reportedErrorEventType, payload["@type"])
+			}
+			if want := "panic: " + c.want.Value; payload["message"] != want {
+				t.Errorf("Expected message %q, got %v", want, payload["message"])
+			}
+			if stack, _ := payload["stack_trace"].(string); !strings.HasPrefix(stack, "goroutine ") {
+				t.Errorf("Expected a stack trace, got %q", stack)
+			}
+
+			got := payload["panic"].(map[string]interface{})
+			if got["kind"] != c.want.Kind || got["type"] != c.want.Type || got["value"] != c.want.Value {
+				t.Errorf("Expected panic %+v, got %v", c.want, got)
+			}
+
+			// httptest.NewRequest sets the remote address to 192.0.2.1:1234.
+			httpReq := payload["context"].(map[string]interface{})["httpRequest"].(map[string]interface{})
+			if httpReq["remoteIp"] != "192.0.2.1" {
+				t.Errorf("Expected remote IP %q without the port, got %v", "192.0.2.1", httpReq["remoteIp"])
+			}
+		})
+	}
+}
+
+func TestWithRecoverPanicsAfterWrite(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("too late")
+	}), WithRecoverPanics(), WithRequestSummary(), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+	req := httptest.NewRequest("GET", "http://example.com/boom", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected the written status %d to be kept, got %d", http.StatusAccepted, rec.Code)
+	}
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected the panic and the summary to be logged, got %d entries", len(entries))
+	}
+}
+
+func TestWithRecoverPanicsAbortHandler(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), WithRecoverPanics(), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+	req := httptest.NewRequest("GET", "http://example.com/boom", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to be panicked again, got %v", v)
+		}
+		if entries := decodeEntries(t, &buf); len(entries) != 0 {
+			t.Errorf("Expected no entries, got %v", entries)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,40 @@
+package gaelog
+
+import (
+	"time"
+)
+
+// A Field is a field of an object payload. Make one with Time, Bytes, or as a literal.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Time returns a field with the given key whose value is t formatted as RFC 3339 with nanoseconds
+// (time.RFC3339Nano), the format Cloud Logging parses, so timestamps are formatted uniformly
+// whatever the type that held them and can be compared in queries.
+func Time(key string, t time.Time) Field {
+	return Field{Key: key, Value: t.Format(time.RFC3339Nano)}
+}
+
+// Fields returns an object payload made of the given fields, for use with Log and the like. Any
+// time.Time values are formatted as by Time, and any []byte values are encoded as by Bytes. If more
+// than one field has the same key then the last one's value is used.
+//
+//	lg.Info(gaelog.Fields(
+//		gaelog.Field{Key: "order", Value: id},
+//		gaelog.Time("shipped", shipped),
+//	))
+func Fields(fields ...Field) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case time.Time:
+			f = Time(f.Key, v)
+		case []byte:
+			f = Bytes(f.Key, v)
+		}
+		m[f.Key] = f.Value
+	}
+	return m
+}
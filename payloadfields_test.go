@@ -0,0 +1,54 @@
+package gaelog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestFields(t *testing.T) {
+	shipped := time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("PST", -8*60*60))
+	got := Fields(
+		Field{Key: "order", Value: 42},
+		Time("shipped", shipped),
+		Field{Key: "delivered", Value: shipped.Add(time.Hour)},
+	)
+
+	want := map[string]interface{}{
+		"order":     42,
+		"shipped":   "2020-01-02T03:04:05.000000006-08:00",
+		"delivered": "2020-01-02T04:04:05.000000006-08:00",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func TestEncoderFormatsTimes(t *testing.T) {
+	// encoding/json formats nested time.Times as RFC 3339 with nanoseconds, as Time does.
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	b, err := GCPEncoder.Encode(logging.Entry{
+		Payload: map[string]interface{}{
+			"nested": struct{ At time.Time }{at},
+			"field":  Fields(Time("at", at))["at"],
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var m struct {
+		Nested struct{ At string }
+		Field  string
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.Nested.At != at.Format(time.RFC3339Nano) || m.Field != m.Nested.At {
+		t.Errorf("Expected both times to be formatted as %q, got %s", at.Format(time.RFC3339Nano), b)
+	}
+}
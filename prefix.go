@@ -0,0 +1,61 @@
+package gaelog
+
+import (
+	"context"
+
+	"cloud.google.com/go/logging"
+)
+
+// ComponentLabel is the key of the label set by WithPrefix.
+const ComponentLabel = "component"
+
+const prefixKey = ctxKeyType("gaelog-prefix")
+
+// prefix is the value stored in a context by WithPrefix.
+type prefix struct {
+	text      string
+	component string
+}
+
+// WithPrefix returns a copy of ctx for use by a sub-component of a handler, such as a billing
+// module, to set apart its entries from those of the rest of the request. The package-level
+// logging functions (Logf, Log, and so on), when called with the returned context, prepend
+// "[component] " to string payloads and set a label with key ComponentLabel on entries with
+// other payloads.
+//
+// WithPrefix may be applied more than once, in which case the prefixes accumulate, e.g.
+// "[billing] [invoices] ", and the label's value is the components joined with slashes, e.g.
+// "billing/invoices".
+func WithPrefix(ctx context.Context, component string) context.Context {
+	p := prefix{
+		text:      "[" + component + "] ",
+		component: component,
+	}
+	if outer, ok := ctx.Value(prefixKey).(prefix); ok {
+		p.text = outer.text + p.text
+		p.component = outer.component + "/" + p.component
+	}
+	return context.WithValue(ctx, prefixKey, p)
+}
+
+// applyPrefix applies the prefix carried by ctx, if any, to e.
+func applyPrefix(ctx context.Context, e *logging.Entry) {
+	p, ok := ctx.Value(prefixKey).(prefix)
+	if !ok {
+		return
+	}
+
+	if s, ok := e.Payload.(string); ok {
+		e.Payload = p.text + s
+		return
+	}
+	e.Labels = withLabel(e.Labels, ComponentLabel, p.component)
+}
+
+// prefixed returns payload with the prefix carried by ctx, if any, prepended if it is a string.
+// It is for logging in fallback mode, where there are no labels.
+func prefixed(ctx context.Context, payload interface{}) interface{} {
+	e := logging.Entry{Payload: payload}
+	applyPrefix(ctx, &e)
+	return e.Payload
+}
@@ -0,0 +1,36 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithPrefix(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	Info(ctx, "no prefix")
+
+	ctx = WithPrefix(ctx, "billing")
+	Infof(ctx, "charged %d", 5)
+
+	ctx = WithPrefix(ctx, "invoices")
+	Info(ctx, "sent")
+	Info(ctx, map[string]interface{}{"count": 1})
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+
+	for i, want := range []string{"no prefix", "[billing] charged 5", "[billing] [invoices] sent"} {
+		if got := entries[i]["message"]; got != want {
+			t.Errorf("Expected entry %d to be %q, got %v", i, want, got)
+		}
+	}
+
+	labels, _ := entries[3]["logging.googleapis.com/labels"].(map[string]interface{})
+	if labels[ComponentLabel] != "billing/invoices" {
+		t.Errorf("Expected label %q to be %q, got %v", ComponentLabel, "billing/invoices", labels)
+	}
+}
@@ -0,0 +1,139 @@
+package gaelog
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A TracePropagator extracts the trace context that an incoming request carries in its headers,
+// so that the request's entries can be correlated with its trace.
+type TracePropagator interface {
+	// Extract returns the ID of the request's trace, the ID of the span that made the request, and
+	// whether the trace is sampled. The trace ID is as expected by Cloud Trace, which is 32
+	// hexadecimal digits, and the span ID is 16 hexadecimal digits or empty if it is unknown. If
+	// the request doesn't carry the propagator's format then the trace ID is empty.
+	Extract(r *http.Request) (trace, span string, sampled bool)
+}
+
+var (
+	// GoogleTracePropagator extracts the trace context from the X-Cloud-Trace-Context header set
+	// by Google Cloud's load balancers and front ends. It is the default.
+	GoogleTracePropagator TracePropagator = googlePropagator{}
+
+	// W3CTracePropagator extracts the trace context from the traceparent header defined by W3C
+	// Trace Context. See https://www.w3.org/TR/trace-context/.
+	W3CTracePropagator TracePropagator = w3cPropagator{}
+
+	// DatadogTracePropagator extracts the trace context from the x-datadog-trace-id,
+	// x-datadog-parent-id, and x-datadog-sampling-priority headers set by Datadog's tracers. The
+	// high 64 bits of 128-bit trace IDs are taken from the _dd.p.tid tag in x-datadog-tags.
+	DatadogTracePropagator TracePropagator = datadogPropagator{}
+)
+
+// WithTracePropagators sets the TracePropagators used to find a request's trace context. They are
+// tried in order and the first to find a trace context wins, so a chain can accept several formats:
+//
+//	gaelog.WithTracePropagators(gaelog.GoogleTracePropagator, gaelog.W3CTracePropagator)
+//
+// A request in which none finds a trace context is treated as one without the
+// X-Cloud-Trace-Context header is by default. If this option is not given then only
+// GoogleTracePropagator is used.
+func WithTracePropagators(propagators ...TracePropagator) Option {
+	return func(cfg *config) {
+		cfg.propagators = propagators
+	}
+}
+
+// extractTrace returns the trace context found by the first of cfg's TracePropagators to find one.
+// The trace ID is empty if none does.
+func (cfg *config) extractTrace(r *http.Request) (trace, span string, sampled bool) {
+	propagators := cfg.propagators
+	if propagators == nil {
+		propagators = []TracePropagator{GoogleTracePropagator}
+	}
+
+	for _, p := range propagators {
+		if trace, span, sampled = p.Extract(r); trace != "" {
+			return trace, span, sampled
+		}
+	}
+	return "", "", false
+}
+
+// noTraceError returns the error for a request in which cfg's TracePropagators found no trace
+// context.
+func (cfg *config) noTraceError() error {
+	if cfg.propagators == nil {
+		return fmt.Errorf("gaelog: %s header is not set, falling back to standard library log", traceContextHeaderName)
+	}
+	return fmt.Errorf("gaelog: request has no trace context, falling back to standard library log")
+}
+
+type googlePropagator struct{}
+
+func (googlePropagator) Extract(r *http.Request) (trace, span string, sampled bool) {
+	trace, span, sampled = parseTraceContext(r.Header.Get(traceContextHeaderName))
+	return trace, hexSpanID(span), sampled
+}
+
+type w3cPropagator struct{}
+
+func (w3cPropagator) Extract(r *http.Request) (trace, span string, sampled bool) {
+	// The header has the form "VERSION-TRACE_ID-PARENT_ID-FLAGS". Later versions may append
+	// fields, which are ignored.
+	parts := strings.Split(strings.TrimSpace(r.Header.Get("traceparent")), "-")
+	if len(parts) < 4 || parts[0] == "ff" || !isHex(parts[0], 2) {
+		return "", "", false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", "", false
+	}
+	trace, span = parts[1], parts[2]
+	if !isHexID(trace, 32) || !isHexID(span, 16) || !isHex(parts[3], 2) {
+		return "", "", false
+	}
+
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	return trace, span, flags&1 == 1
+}
+
+type datadogPropagator struct{}
+
+func (datadogPropagator) Extract(r *http.Request) (trace, span string, sampled bool) {
+	low, err := strconv.ParseUint(r.Header.Get("x-datadog-trace-id"), 10, 64)
+	if err != nil || low == 0 {
+		return "", "", false
+	}
+
+	high := "0000000000000000"
+	for _, tag := range strings.Split(r.Header.Get("x-datadog-tags"), ",") {
+		tag = strings.TrimSpace(tag)
+		if v := strings.TrimPrefix(tag, "_dd.p.tid="); v != tag && isHexID(v, 16) {
+			high = v
+		}
+	}
+
+	priority, _ := strconv.Atoi(r.Header.Get("x-datadog-sampling-priority"))
+	return fmt.Sprintf("%s%016x", high, low), hexSpanID(r.Header.Get("x-datadog-parent-id")), priority > 0
+}
+
+// isHex reports whether s is n lowercase hexadecimal digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isHexID reports whether s is n lowercase hexadecimal digits, not all zero, as valid trace and
+// span IDs are.
+func isHexID(s string, n int) bool {
+	return isHex(s, n) && strings.Trim(s, "0") != ""
+}
@@ -0,0 +1,74 @@
+package gaelog
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracePropagators(t *testing.T) {
+	cases := []struct {
+		name        string
+		p           TracePropagator
+		headers     map[string]string
+		wantTrace   string
+		wantSpan    string
+		wantSampled bool
+	}{
+		{"google", GoogleTracePropagator, map[string]string{"X-Cloud-Trace-Context": "abcdef0123456789/123;o=1"}, "abcdef0123456789", "000000000000007b", true},
+		{"google_absent", GoogleTracePropagator, nil, "", "", false},
+		{"w3c", W3CTracePropagator, map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"w3c_unsampled", W3CTracePropagator, map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"}, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", false},
+		{"w3c_future_version", W3CTracePropagator, map[string]string{"traceparent": "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"}, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"w3c_zero_trace", W3CTracePropagator, map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"}, "", "", false},
+		{"w3c_uppercase", W3CTracePropagator, map[string]string{"traceparent": "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"}, "", "", false},
+		{"w3c_invalid_version", W3CTracePropagator, map[string]string{"traceparent": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "", "", false},
+		{"datadog", DatadogTracePropagator, map[string]string{"x-datadog-trace-id": "123", "x-datadog-parent-id": "456", "x-datadog-sampling-priority": "1"}, "0000000000000000000000000000007b", "00000000000001c8", true},
+		{"datadog_128_bit", DatadogTracePropagator, map[string]string{"x-datadog-trace-id": "123", "x-datadog-tags": "_dd.p.dm=-1,_dd.p.tid=640cfd8d00000000"}, "640cfd8d00000000000000000000007b", "", false},
+		{"datadog_dropped", DatadogTracePropagator, map[string]string{"x-datadog-trace-id": "123", "x-datadog-sampling-priority": "-1"}, "0000000000000000000000000000007b", "", false},
+		{"datadog_absent", DatadogTracePropagator, nil, "", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://example.com", nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+
+			trace, span, sampled := c.p.Extract(r)
+			if trace != c.wantTrace || span != c.wantSpan || sampled != c.wantSampled {
+				t.Errorf("Extract() = (%q, %q, %v), want (%q, %q, %v)",
+					trace, span, sampled, c.wantTrace, c.wantSpan, c.wantSampled)
+			}
+		})
+	}
+}
+
+func TestWithTracePropagators(t *testing.T) {
+	setGAEEnvVars(t)
+
+	options := []Option{WithTracePropagators(W3CTracePropagator, GoogleTracePropagator), WithSink(nopLogger{})}
+
+	r := httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	lg, err := NewWithOptions(r, options...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(lg.trace, "/traces/4bf92f3577b34da6a3ce929d0e0e4736") || lg.spanID != "00f067aa0ba902b7" {
+		t.Errorf("Expected the first propagator to win, got trace %q and span %q", lg.trace, lg.spanID)
+	}
+
+	r = httptest.NewRequest("GET", "https://example.com", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	if lg, err := NewWithOptions(r, options...); err != nil || !strings.HasSuffix(lg.trace, "/traces/abcdef0123456789") {
+		t.Errorf("Expected the second propagator to be tried, got trace %q (%v)", lg.trace, err)
+	}
+
+	r = httptest.NewRequest("GET", "https://example.com", nil)
+	if lg, err := NewWithOptions(r, options...); err == nil || !lg.IsFallback() {
+		t.Error("Expected a request without a trace context to fall back")
+	}
+}
@@ -0,0 +1,68 @@
+package gaelog
+
+import (
+	"sync"
+	"time"
+)
+
+// WithRateLimit caps the rate at which entries are logged, whatever their severity, as a safeguard
+// for the logging quota of a service that misbehaves under load. It is a token bucket: up to burst
+// entries may be logged at once, and the allowance refills at perSecond entries per second.
+// Entries beyond it are dropped. They are counted under "rate_limited" by StartDropReport, which
+// reports them periodically without correlating the report with any request.
+//
+// The limit is that of the returned Option rather than of each Logger: it is shared by all Loggers
+// created with the Option, so for a wrapped handler, whose Logger is made for each request, it
+// applies across requests. Loggers created with separate calls to WithRateLimit have separate
+// limits. See WithMaxEntriesPerRequest for a per-request cap.
+func WithRateLimit(perSecond float64, burst int) Option {
+	l := &rateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+	return func(cfg *config) {
+		cfg.rateLimit = l
+	}
+}
+
+// rateLimiter is the token bucket of WithRateLimit.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// allow reports whether an entry may be logged at time now.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// allowRate reports whether an entry is within the rate set with WithRateLimit.
+func (lg *Logger) allowRate() bool {
+	l := lg.config().rateLimit
+	return l == nil || l.allow(time.Now())
+}
+
+// withoutRateLimit lifts the limit set with WithRateLimit.
+func withoutRateLimit(cfg *config) {
+	cfg.rateLimit = nil
+}
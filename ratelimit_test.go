@@ -0,0 +1,71 @@
+package gaelog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{rate: 2, burst: 2, tokens: 2}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{100 * time.Millisecond, false},
+		// Half a second refills one token.
+		{500 * time.Millisecond, true},
+		{500 * time.Millisecond, false},
+		// The bucket holds no more than the burst however long it has been.
+		{time.Hour, true},
+		{time.Hour, true},
+		{time.Hour, false},
+	}
+
+	for i, s := range steps {
+		if got := l.allow(start.Add(s.at)); got != s.want {
+			t.Errorf("Step %d: expected %v, got %v", i, s.want, got)
+		}
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	dropCounts[dropRateLimited].Store(0)
+
+	rec := &recordingLogger{}
+	lg := &Logger{cfg: newConfig([]Option{WithRateLimit(1, 3)}), logger: rec}
+	for i := 0; i < 5; i++ {
+		lg.Infof("entry %d", i)
+	}
+
+	if len(rec.payloads) != 3 {
+		t.Errorf("Expected 3 entries within the burst, got %d", len(rec.payloads))
+	}
+	if n := dropCounts[dropRateLimited].Load(); n != 2 {
+		t.Errorf("Expected 2 entries counted as rate limited, got %d", n)
+	}
+}
+
+func TestReportDropsRateLimited(t *testing.T) {
+	for reason := range dropCounts {
+		dropCounts[reason].Store(0)
+	}
+
+	rec := &recordingLogger{}
+	lg := &Logger{cfg: newConfig([]Option{WithRateLimit(1, 1)}), logger: rec}
+	lg.Info("kept")
+	lg.Info("dropped")
+	lg.reportDrops(time.Minute)
+
+	if len(rec.payloads) != 2 {
+		t.Fatalf("Expected the entry within the limit and the report, got %v", rec.payloads)
+	}
+	payload := rec.payloads[1].(map[string]interface{})
+	if payload["rate_limited"] != int64(1) {
+		t.Errorf("Expected rate_limited to be 1, got %v", payload["rate_limited"])
+	}
+}
@@ -0,0 +1,40 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// LogWithResource is like Log but attributes the entry to the given monitored resource rather than
+// to the Logger's, for services such as gateways that act for several logical resources. The entry
+// is otherwise correlated with the request as usual. If res is nil then the Logger's resource is
+// used.
+func (lg *Logger) LogWithResource(severity Severity, res *monitoredres.MonitoredResource, v interface{}) {
+	lg.logWithResource(context.Background(), severity, res, v)
+}
+
+// LogWithResource calls LogWithResource on the Logger in ctx. This should be called from a handler
+// that has been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been
+// wrapped then v is simply logged using the standard library's log package.
+func LogWithResource(ctx context.Context, severity Severity, res *monitoredres.MonitoredResource, v interface{}) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	logger.logWithResource(ctx, severity, res, v)
+}
+
+func (lg *Logger) logWithResource(ctx context.Context, severity Severity, res *monitoredres.MonitoredResource, v interface{}) {
+	if lg.logger == nil {
+		log.Print(prefixed(ctx, lg.config().fallbackPayload(v)))
+		return
+	}
+
+	e := lg.entry(severity, v)
+	if res != nil {
+		e.Resource = res
+	}
+	lg.writeContext(ctx, e)
+}
@@ -0,0 +1,43 @@
+package gaelog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+func TestLogWithResource(t *testing.T) {
+	setGAEEnvVars(t)
+
+	backend := &monitoredres.MonitoredResource{
+		Type:   "generic_task",
+		Labels: map[string]string{"job": "billing"},
+	}
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LogWithResource(r.Context(), SeverityInfo, backend, "proxied")
+		LogWithResource(r.Context(), SeverityInfo, nil, "own resource")
+	}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(rec.entries))
+	}
+	if got := rec.entries[0].Resource; got != backend {
+		t.Errorf("Expected resource %v, got %v", backend, got)
+	}
+	if got := rec.entries[1].Resource; got.GetType() != GAEAppResourceType {
+		t.Errorf("Expected resource of type %q, got %v", GAEAppResourceType, got)
+	}
+	for _, e := range rec.entries {
+		if want := "projects/" + testProjectID + "/traces/abcdef0123456789"; e.Trace != want {
+			t.Errorf("Expected trace %q, got %q", want, e.Trace)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gaelog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// responseWriter wraps an http.ResponseWriter to record the status code and the number of bytes
+// written, for use in entries logged once the request completes.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status int
+	size   int64
+
+	// firstByte is when the status was written, whether explicitly or by the first Write.
+	firstByte time.Time
+
+	// hijacked is whether the connection has been hijacked, after which the status and size
+	// are unknown.
+	hijacked bool
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+// setStatus records the status and the time it was written.
+func (w *responseWriter) setStatus(status int) {
+	w.status = status
+	w.firstByte = time.Now()
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.setStatus(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.setStatus(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped ResponseWriter does.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.setStatus(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. If the wrapped ResponseWriter does not implement it then
+// http.ErrNotSupported is returned.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher if the wrapped ResponseWriter does, as those of HTTP/2 servers do.
+// Otherwise http.ErrNotSupported is returned.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// Unwrap returns the wrapped ResponseWriter, for use by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the status code written, defaulting to 200 as net/http does if the handler
+// wrote nothing.
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// requestBody wraps a request's body to count the bytes read from it, for requests whose size
+// isn't given by the Content-Length header, such as those with chunked encoding.
+type requestBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// wrapRequestBody returns a requestBody wrapping r's body and sets it as r's body, or returns nil
+// if r has no body.
+func wrapRequestBody(r *http.Request) *requestBody {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	b := &requestBody{ReadCloser: r.Body}
+	r.Body = b
+	return b
+}
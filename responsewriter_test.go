@@ -0,0 +1,144 @@
+package gaelog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newResponseWriter(rec)
+
+	if got := w.statusCode(); got != http.StatusOK {
+		t.Errorf("Expected default status %d, got %d", http.StatusOK, got)
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("hello"))
+	w.Write([]byte(" world"))
+
+	if got := w.statusCode(); got != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, got)
+	}
+	if w.size != 11 {
+		t.Errorf("Expected size 11, got %d", w.size)
+	}
+
+	w.Flush()
+	if !rec.Flushed {
+		t.Errorf("Expected Flush to be forwarded")
+	}
+}
+
+// hijackableRecorder is a ResponseRecorder that supports hijacking, as the ResponseWriters of
+// HTTP/1.x servers do.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	w := newResponseWriter(httptest.NewRecorder())
+	if _, _, err := w.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if w.hijacked {
+		t.Error("Expected a failed hijack not to be recorded")
+	}
+
+	w = newResponseWriter(hijackableRecorder{httptest.NewRecorder()})
+	conn, _, err := w.Hijack()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+	if !w.hijacked {
+		t.Error("Expected the hijack to be recorded")
+	}
+}
+
+// pushRecorder is a ResponseRecorder that supports server push, as the ResponseWriters of HTTP/2
+// servers do.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestResponseWriterPush(t *testing.T) {
+	rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := newResponseWriter(rec).Push("/style.css", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rec.pushed) != 1 || rec.pushed[0] != "/style.css" {
+		t.Errorf("Expected the push to be forwarded, got %v", rec.pushed)
+	}
+
+	if err := newResponseWriter(httptest.NewRecorder()).Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Errorf("Expected %v, got %v", http.ErrNotSupported, err)
+	}
+}
+
+func TestWrapStreamsOverHTTP2(t *testing.T) {
+	setGAEEnvVars(t)
+
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	releaseHandler := func() { releaseOnce.Do(func() { close(release) }) }
+	// Don't hang if the flush doesn't go through.
+	defer time.AfterFunc(5*time.Second, releaseHandler).Stop()
+
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("Expected an HTTP/2 request, got %s", r.Proto)
+		}
+		if _, ok := w.(http.Pusher); !ok {
+			t.Error("Expected the wrapped ResponseWriter to implement http.Pusher")
+		}
+
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("second"))
+	}), WithRequestSummary(), WithSink(nopLogger{}))
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The first write arrives while the handler is still running only if the flush went through.
+	buf := make([]byte, len("first"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "first" {
+		t.Errorf("Expected %q before the handler returned, got %q (%v)", "first", buf, err)
+	}
+	releaseHandler()
+
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil || string(rest) != "second" {
+		t.Errorf("Expected %q, got %q (%v)", "second", rest, err)
+	}
+}
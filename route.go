@@ -0,0 +1,79 @@
+package gaelog
+
+import (
+	"context"
+	"net/http"
+)
+
+// EndpointLabel is the key of the label set by WithRouteLabel.
+const EndpointLabel = "endpoint"
+
+// A RouteFunc returns the pattern of the route that matched a request, such as "/users/{id}", or
+// the empty string if no route has matched it (yet). Routers record the matched route in their own
+// ways; the chiroute and muxroute packages provide RouteFuncs for chi and gorilla/mux.
+type RouteFunc func(r *http.Request) string
+
+// WithRouteLabel sets a label with key EndpointLabel on every entry logged by the Logger, whose
+// value is the pattern of the route that matched the request as returned by fn. Route patterns
+// make for much better filtering by endpoint than paths, which vary with the route's parameters.
+//
+// Routers match a request as they handle it, so fn is called as entries are logged until it
+// returns a pattern, which is then used for the rest of the request; entries logged before the
+// route is matched do not have the label. For the same reason, when using WrapWithOptions the
+// handler should be installed as middleware of the router (e.g. with the router's Use method)
+// rather than wrapping the router, so that the request it sees is the one the router matches.
+func WithRouteLabel(fn RouteFunc) Option {
+	return func(cfg *config) {
+		cfg.route = fn
+	}
+}
+
+// entryLabels returns the labels to set on the Logger's entries, including EndpointLabel once the
+// request's route is known.
+func (lg *Logger) entryLabels() map[string]string {
+	fn := lg.config().route
+	if fn == nil || lg.req == nil {
+		return lg.labels
+	}
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	if lg.routeLabels == nil {
+		if pattern := fn(lg.req); pattern != "" {
+			lg.routeLabels = withLabel(lg.labels, EndpointLabel, pattern)
+		}
+	}
+	if lg.routeLabels != nil {
+		return lg.routeLabels
+	}
+	return lg.labels
+}
+
+// route returns the pattern of the route that matched the Logger's request, as returned by the
+// RouteFunc given to WithRouteLabel or, without one, by ServeMuxPattern. It is empty if no route
+// has matched the request (yet) or the Logger has no request, as is the case for Loggers created
+// with New and its variants without WithRouteLabel.
+func (lg *Logger) route() string {
+	if lg.req == nil {
+		return ""
+	}
+	fn := lg.config().route
+	if fn == nil {
+		fn = ServeMuxPattern
+	}
+	return fn(lg.req)
+}
+
+// Route returns the method of the request whose Logger is in ctx and the pattern of the route that
+// matched it, as found by the RouteFunc given to WithRouteLabel or, without one, by
+// ServeMuxPattern. It is for code deep in a handler that would include the route in its entries.
+// The pattern is empty if no route has matched the request yet, and both are empty if ctx has no
+// Logger.
+func Route(ctx context.Context) (method, pattern string) {
+	logger := loggerFromContext(ctx)
+	if logger == nil || logger.req == nil {
+		return "", ""
+	}
+	return logger.req.Method, logger.route()
+}
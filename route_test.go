@@ -0,0 +1,35 @@
+package gaelog
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWithRouteLabel(t *testing.T) {
+	var pattern string
+	lg, buf := newRedirectedLogger(t, WithLabels(map[string]string{"a": "b"}), WithRouteLabel(func(r *http.Request) string {
+		return pattern
+	}))
+
+	lg.Info("before routing")
+	pattern = "/users/{id}"
+	lg.Info("after routing")
+	pattern = "/changed"
+	lg.Info("later")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	wantEndpoints := []interface{}{nil, "/users/{id}", "/users/{id}"}
+	for i, e := range entries {
+		labels, _ := e["logging.googleapis.com/labels"].(map[string]interface{})
+		if labels["a"] != "b" {
+			t.Errorf("Entry %d: expected the Logger's labels, got %v", i, labels)
+		}
+		if labels[EndpointLabel] != wantEndpoints[i] {
+			t.Errorf("Entry %d: expected endpoint %v, got %v", i, wantEndpoints[i], labels[EndpointLabel])
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package gaelog
+
+import (
+	"os"
+	"runtime"
+)
+
+// RuntimeLabel is the key of the label set by WithRuntimeLabel.
+const RuntimeLabel = "runtime"
+
+// WithRuntimeLabel sets a label with key RuntimeLabel on every entry logged by the Logger so that
+// behavior can be correlated with the runtime the app runs on, such as when juggling a runtime
+// upgrade. On App Engine its value is the runtime given by the GAE_RUNTIME environment variable,
+// such as "go121". Elsewhere, such as on Cloud Run, where the app brings its own runtime, it is
+// the version of Go the app was built with, such as "go1.21.3" (see runtime.Version).
+func WithRuntimeLabel() Option {
+	return func(cfg *config) {
+		cfg.labels = withLabel(cfg.labels, RuntimeLabel, runtimeName())
+	}
+}
+
+// runtimeName returns the value of the label set by WithRuntimeLabel.
+func runtimeName() string {
+	if rt := os.Getenv("GAE_RUNTIME"); rt != "" {
+		return rt
+	}
+	return runtime.Version()
+}
@@ -0,0 +1,22 @@
+package gaelog
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestWithRuntimeLabel(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GAE_RUNTIME": "go121",
+	})
+	cfg := newConfig([]Option{WithRuntimeLabel()})
+	unset()
+	if got := cfg.labels[RuntimeLabel]; got != "go121" {
+		t.Errorf("Expected label %q to be %q, got %q", RuntimeLabel, "go121", got)
+	}
+
+	cfg = newConfig([]Option{WithRuntimeLabel()})
+	if got := cfg.labels[RuntimeLabel]; got != runtime.Version() {
+		t.Errorf("Expected label %q to be %q, got %q", RuntimeLabel, runtime.Version(), got)
+	}
+}
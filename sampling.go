@@ -0,0 +1,168 @@
+package gaelog
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithErrorSampling keeps all the entries of a request whose handling goes wrong while logging only
+// a sample of the rest, which gives full detail for failed requests at a fraction of the cost.
+//
+// Entries with a severity below trigger are buffered in memory rather than sent. If an entry at or
+// above trigger is logged then the buffered entries are sent, in order, followed by it, and the
+// rest of the request's entries are sent as usual. If the Logger is closed without that happening
+// then the buffered entries are discarded. At most the number of entries set with
+// WithMaxEntriesPerRequest, or DefaultMaxBufferedEntries without it, are buffered; once the buffer
+// is full the oldest entries are discarded to make room.
+//
+// The exception is a sample of traces, the given fraction of them, whose entries are all sent as
+// usual. Which traces are sampled depends only on the trace ID, so all services and instances that
+// handle a trace make the same decision. A fraction of 0 keeps only the entries of requests with an
+// entry at or above trigger; a fraction of 1 keeps all entries.
+//
+// Loggers made from the Logger with Named and Detach are not sampled.
+func WithErrorSampling(trigger Severity, fraction float64) Option {
+	return func(cfg *config) {
+		cfg.sampling = &errorSampling{trigger: trigger, fraction: fraction}
+	}
+}
+
+// DefaultMaxBufferedEntries is the number of entries that a Logger buffers in memory while waiting
+// for its client to be created with WithLazyClient, and that a Logger created with
+// WithErrorSampling buffers when the number isn't set with WithMaxEntriesPerRequest.
+const DefaultMaxBufferedEntries = 1000
+
+// errorSampling holds the settings given to WithErrorSampling.
+type errorSampling struct {
+	trigger  Severity
+	fraction float64
+}
+
+// sampled reports whether all entries of the given trace are kept regardless of severity.
+func (s *errorSampling) sampled(trace string) bool {
+	return traceSampled(trace, s.fraction)
+}
+
+// traceSampled reports whether the given trace is among the given fraction of traces that are
+// sampled. The decision depends only on the trace ID, and a trace sampled at some fraction is
+// sampled at every greater one.
+func traceSampled(trace string, fraction float64) bool {
+	if fraction >= 1 {
+		return true
+	}
+	if fraction <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(trace))
+	return float64(mix64(h.Sum64())) < fraction*math.MaxUint64
+}
+
+// mix64 is the finalizer of MurmurHash3, which spreads the bits of FNV hashes of similar strings,
+// such as trace IDs that differ only in their last digits, across the whole range.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// samplingLogger is an entryLogger that buffers entries until one is at or above the trigger
+// severity.
+type samplingLogger struct {
+	next    entryLogger
+	trigger Severity
+	limit   int
+
+	mu        sync.Mutex
+	triggered bool
+	pending   []logging.Entry
+}
+
+// sample wraps the Logger's underlying logger as its config's sampling calls for.
+func (lg *Logger) sample() {
+	s := lg.config().sampling
+	if s == nil || lg.logger == nil {
+		return
+	}
+
+	// Decide using the trace ID alone, without the project that lg.trace is qualified with.
+	trace := lg.trace[strings.LastIndexByte(lg.trace, '/')+1:]
+	if s.sampled(trace) {
+		return
+	}
+
+	limit := lg.config().maxEntries
+	if limit <= 0 {
+		limit = DefaultMaxBufferedEntries
+	}
+	lg.sampler = &samplingLogger{next: lg.logger, trigger: s.trigger, limit: limit}
+	lg.logger = lg.sampler
+}
+
+// unsampled returns the Logger's underlying logger without any sampling.
+func (lg *Logger) unsampled() entryLogger {
+	if lg.sampler != nil {
+		return lg.sampler.next
+	}
+	return lg.logger
+}
+
+// release reports whether e should be passed on, first passing on the buffered entries if e
+// triggers it. If e should not be passed on then it is buffered.
+func (l *samplingLogger) release(e logging.Entry) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.triggered {
+		return true
+	}
+	if e.Severity < l.trigger {
+		if len(l.pending) >= l.limit {
+			l.pending = l.pending[1:]
+			countDrop(dropSampled, 1)
+		}
+		l.pending = append(l.pending, e)
+		return false
+	}
+
+	l.triggered = true
+	for _, p := range l.pending {
+		l.next.Log(p)
+	}
+	l.pending = nil
+	return true
+}
+
+func (l *samplingLogger) Log(e logging.Entry) {
+	if l.release(e) {
+		l.next.Log(e)
+	}
+}
+
+func (l *samplingLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	if l.release(e) {
+		return l.next.LogSync(ctx, e)
+	}
+	return nil
+}
+
+func (l *samplingLogger) Flush() error {
+	return l.next.Flush()
+}
+
+// discard drops the buffered entries.
+func (l *samplingLogger) discard() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	countDrop(dropSampled, len(l.pending))
+	l.pending = nil
+}
@@ -0,0 +1,109 @@
+package gaelog
+
+import (
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+// recordingLogger is an entryLogger that records the payloads of entries.
+type recordingLogger struct {
+	nopLogger
+	payloads []interface{}
+}
+
+func (l *recordingLogger) Log(e logging.Entry) {
+	l.payloads = append(l.payloads, e.Payload)
+}
+
+func newSamplingTestLogger(trigger Severity, fraction float64) (*Logger, *recordingLogger) {
+	rec := &recordingLogger{}
+	lg := &Logger{
+		cfg:    newConfig([]Option{WithErrorSampling(trigger, fraction)}),
+		logger: rec,
+		trace:  traceID(testProjectID, "abcdef0123456789"),
+	}
+	lg.sample()
+	return lg, rec
+}
+
+func TestWithErrorSamplingTriggered(t *testing.T) {
+	lg, rec := newSamplingTestLogger(SeverityError, 0)
+
+	lg.Debug("one")
+	lg.Info("two")
+	if len(rec.payloads) != 0 {
+		t.Fatalf("Expected entries to be buffered, got %v", rec.payloads)
+	}
+
+	lg.Error("three")
+	lg.Info("four")
+	lg.Close()
+
+	want := []interface{}{"one", "two", "three", "four"}
+	if fmt.Sprint(rec.payloads) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, rec.payloads)
+	}
+}
+
+func TestWithErrorSamplingDiscarded(t *testing.T) {
+	lg, rec := newSamplingTestLogger(SeverityError, 0)
+
+	lg.Info("one")
+	lg.Warning("two")
+	lg.Close()
+
+	if len(rec.payloads) != 0 {
+		t.Errorf("Expected entries to be discarded, got %v", rec.payloads)
+	}
+}
+
+func TestWithErrorSamplingSampledTrace(t *testing.T) {
+	lg, rec := newSamplingTestLogger(SeverityError, 1)
+
+	lg.Info("one")
+	if len(rec.payloads) != 1 {
+		t.Errorf("Expected the entries of a sampled trace to be sent, got %v", rec.payloads)
+	}
+}
+
+func TestErrorSamplingSampled(t *testing.T) {
+	s := &errorSampling{fraction: 0.25}
+
+	kept := 0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		trace := fmt.Sprintf("%032x", i)
+		if s.sampled(trace) != s.sampled(trace) {
+			t.Fatalf("Expected the decision for trace %s to be consistent", trace)
+		}
+		if s.sampled(trace) {
+			kept++
+		}
+	}
+
+	if kept < n/5 || kept > n*3/10 {
+		t.Errorf("Expected about a quarter of traces to be sampled, got %d of %d", kept, n)
+	}
+}
+
+func TestWithErrorSamplingBufferLimit(t *testing.T) {
+	dropCounts[dropSampled].Store(0)
+
+	lg, rec := newSamplingTestLogger(SeverityError, 0)
+	for i := 0; i <= DefaultMaxBufferedEntries; i++ {
+		lg.Info(i)
+	}
+	lg.Error("error")
+
+	if len(rec.payloads) != DefaultMaxBufferedEntries+1 {
+		t.Fatalf("Expected %d entries, got %d", DefaultMaxBufferedEntries+1, len(rec.payloads))
+	}
+	if rec.payloads[0] != 1 {
+		t.Errorf("Expected the oldest entry to be discarded, got first entry %v", rec.payloads[0])
+	}
+	if n := dropCounts[dropSampled].Load(); n != 1 {
+		t.Errorf("Expected 1 entry counted as dropped, got %d", n)
+	}
+}
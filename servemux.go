@@ -0,0 +1,26 @@
+//go:build go1.23
+
+package gaelog
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ServeMuxPattern is a RouteFunc for the http.ServeMux, which records the pattern that matched a
+// request in its Pattern field as of Go 1.23. It returns the pattern that matched r without its
+// method, if any, such as "/users/{id}" for the pattern "GET /users/{id}", so that apps on the
+// standard library's router get route patterns without a third-party one. It is used by
+// WithRequestSummary and Route when WithRouteLabel isn't given.
+//
+// The ServeMux records the pattern on the request it is given, so, unlike with other routers, the
+// ServeMux should be wrapped with WrapWithOptions rather than the other way around. Note that
+// programs whose go.mod declares a Go version before 1.22 get the ServeMux of Go 1.21, which
+// doesn't record patterns, unless they set GODEBUG=httpmuxgo121=0.
+func ServeMuxPattern(r *http.Request) string {
+	pattern := r.Pattern
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	return pattern
+}
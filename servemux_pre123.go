@@ -0,0 +1,14 @@
+//go:build !go1.23
+
+package gaelog
+
+import (
+	"net/http"
+)
+
+// ServeMuxPattern is a RouteFunc for the http.ServeMux, which records the pattern that matched a
+// request in its Pattern field as of Go 1.23. Before Go 1.23 there is no such field, so it always
+// returns the empty string.
+func ServeMuxPattern(r *http.Request) string {
+	return ""
+}
@@ -0,0 +1,57 @@
+//go:build go1.23
+
+// The module's go.mod predates Go 1.22, so its tests get the old ServeMux without this.
+//go:debug httpmuxgo121=0
+
+package gaelog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeMuxPattern(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"":                         "",
+		"/users/{id}":              "/users/{id}",
+		"GET /users/{id}":          "/users/{id}",
+		"GET  example.com/{$}":     "example.com/{$}",
+		"POST\t/users/{id}/avatar": "/users/{id}/avatar",
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Pattern = pattern
+		if got := ServeMuxPattern(r); got != want {
+			t.Errorf("ServeMuxPattern with pattern %q = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestWrapServeMux(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var method, pattern string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		method, pattern = Route(r.Context())
+	})
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(mux, WithRequestSummary(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com/users/42", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if method != "GET" || pattern != "/users/{id}" {
+		t.Errorf("Expected Route to return GET and /users/{id}, got %q and %q", method, pattern)
+	}
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	payload, _ := rec.entries[0].Payload.(map[string]interface{})
+	if payload["route"] != "/users/{id}" || payload["method"] != "GET" {
+		t.Errorf("Expected the summary to include the route, got %v", rec.entries[0].Payload)
+	}
+}
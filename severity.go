@@ -0,0 +1,43 @@
+package gaelog
+
+import (
+	"cloud.google.com/go/logging"
+)
+
+// Severity is the severity of a log entry. It is an alias of the type from
+// cloud.google.com/go/logging so that severities may be passed to Logf and Log
+// without depending on that package directly.
+type Severity = logging.Severity
+
+// Severities, in increasing order. These are the same values as those of the same
+// names in cloud.google.com/go/logging.
+const (
+	SeverityDefault   = logging.Default
+	SeverityDebug     = logging.Debug
+	SeverityInfo      = logging.Info
+	SeverityNotice    = logging.Notice
+	SeverityWarning   = logging.Warning
+	SeverityError     = logging.Error
+	SeverityCritical  = logging.Critical
+	SeverityAlert     = logging.Alert
+	SeverityEmergency = logging.Emergency
+)
+
+// WithMinSeverity makes the Logger discard entries with a severity below min, such as to omit
+// debug entries in production. Entries with SeverityDefault are discarded too unless min is
+// SeverityDefault, which is the default. Messages logged in fallback mode are unaffected.
+func WithMinSeverity(min Severity) Option {
+	return func(cfg *config) {
+		cfg.minSeverity = min
+	}
+}
+
+// WithDefaultSeverity makes the Logger log entries whose severity is SeverityDefault, which Cloud
+// Logging treats as unspecified, at the given severity instead, so that code that doesn't specify a
+// severity, such as lines from the standard library's log package (see WithStdLogBridge), is logged
+// predictably. The promoted severity is subject to WithMinSeverity like any other.
+func WithDefaultSeverity(severity Severity) Option {
+	return func(cfg *config) {
+		cfg.defaultSeverity = severity
+	}
+}
@@ -0,0 +1,68 @@
+package gaelog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeverityOrder(t *testing.T) {
+	severities := []Severity{
+		SeverityDefault,
+		SeverityDebug,
+		SeverityInfo,
+		SeverityNotice,
+		SeverityWarning,
+		SeverityError,
+		SeverityCritical,
+		SeverityAlert,
+		SeverityEmergency,
+	}
+
+	for i := 1; i < len(severities); i++ {
+		if severities[i-1] >= severities[i] {
+			t.Errorf("Expected %v < %v", severities[i-1], severities[i])
+		}
+	}
+}
+
+func TestLogfWithSeverityConstant(t *testing.T) {
+	// This is mostly a compile-time check that the constants may be passed without
+	// importing cloud.google.com/go/logging.
+	var s Severity = SeverityWarning
+	Logf(context.Background(), s, "severity %v", s)
+}
+
+func TestWithMinSeverity(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMinSeverity(SeverityInfo))
+
+	lg.Log(SeverityDefault, "default")
+	lg.Debugf("debug")
+	lg.Info("info")
+	lg.Errorf("error")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0]["message"] != "info" || entries[1]["message"] != "error" {
+		t.Errorf("Expected the info and error entries, got %v", entries)
+	}
+}
+
+func TestWithDefaultSeverity(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithDefaultSeverity(SeverityInfo), WithMinSeverity(SeverityInfo))
+
+	lg.Log(SeverityDefault, "default")
+	lg.Logf(SeverityDefault, "default %d", 2)
+	lg.Warning("warning")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"INFO", "INFO", "WARNING"} {
+		if entries[i]["severity"] != want {
+			t.Errorf("Expected entry %d to have severity %s, got %v", i, want, entries[i]["severity"])
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package gaelog
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/logging"
+)
+
+// SeveritySamplingEnvVar is the environment variable that sets the default for
+// WithSeveritySampling, so that operators can tune how much is logged at deploy time without
+// changing code. Its value is a comma-separated list of severities and fractions, such as
+// "INFO:0.1,DEBUG:0.01". It is read once, when first needed; if it is malformed then a warning is
+// logged and it is ignored.
+const SeveritySamplingEnvVar = "GAELOG_SAMPLE"
+
+// WithSeveritySampling makes the Logger keep entries of the given severities only for a sample of
+// traces, the given fraction of them, and discard them for the rest. For example, to keep info
+// entries for a tenth of requests and debug entries for a hundredth:
+//
+//	gaelog.WithSeveritySampling(map[gaelog.Severity]float64{
+//		gaelog.SeverityInfo:  0.1,
+//		gaelog.SeverityDebug: 0.01,
+//	})
+//
+// Entries of other severities are all kept. As with WithErrorSampling, which traces are sampled
+// depends only on the trace ID, so a request's entries of a given severity are kept or discarded
+// together, and a trace sampled at some fraction is sampled at every greater one. The default is
+// taken from SeveritySamplingEnvVar. Messages logged in fallback mode are unaffected.
+func WithSeveritySampling(fractions map[Severity]float64) Option {
+	return func(cfg *config) {
+		cfg.severitySampling = fractions
+	}
+}
+
+// keepSeverity reports whether an entry with the given severity is kept per the fractions given
+// to WithSeveritySampling.
+func (lg *Logger) keepSeverity(severity Severity) bool {
+	fraction, ok := lg.config().severitySampling[severity]
+	if !ok {
+		return true
+	}
+	return traceSampled(lg.trace[strings.LastIndexByte(lg.trace, '/')+1:], fraction)
+}
+
+var envSeveritySampling struct {
+	once      sync.Once
+	fractions map[Severity]float64
+}
+
+// severitySamplingFromEnv returns the fractions given by SeveritySamplingEnvVar, memoizing them for
+// use on all but the first call.
+func severitySamplingFromEnv() map[Severity]float64 {
+	envSeveritySampling.once.Do(func() {
+		s := os.Getenv(SeveritySamplingEnvVar)
+		if s == "" {
+			return
+		}
+
+		fractions, err := parseSeveritySampling(s)
+		if err != nil {
+			log.Printf("gaelog: ignoring $%s: %v", SeveritySamplingEnvVar, err)
+			return
+		}
+		envSeveritySampling.fractions = fractions
+	})
+	return envSeveritySampling.fractions
+}
+
+// parseSeveritySampling parses the value of SeveritySamplingEnvVar.
+func parseSeveritySampling(s string) (map[Severity]float64, error) {
+	fractions := make(map[Severity]float64)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form SEVERITY:FRACTION", pair)
+		}
+
+		severity := logging.ParseSeverity(name)
+		if severity == SeverityDefault && !strings.EqualFold(name, "default") {
+			return nil, fmt.Errorf("unknown severity %q", name)
+		}
+
+		fraction, err := strconv.ParseFloat(value, 64)
+		if err != nil || fraction < 0 || fraction > 1 {
+			return nil, fmt.Errorf("fraction %q for %s is not a number between 0 and 1", value, name)
+		}
+		fractions[severity] = fraction
+	}
+	return fractions, nil
+}
@@ -0,0 +1,55 @@
+package gaelog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWithSeveritySampling(t *testing.T) {
+	for _, c := range []struct {
+		fraction float64
+		want     []interface{}
+	}{
+		{0, []interface{}{"warning", "error"}},
+		{1, []interface{}{"debug", "info", "warning", "error"}},
+	} {
+		t.Run(fmt.Sprint(c.fraction), func(t *testing.T) {
+			rec := &recordingLogger{}
+			lg := &Logger{
+				cfg: newConfig([]Option{WithSeveritySampling(map[Severity]float64{
+					SeverityDebug: c.fraction,
+					SeverityInfo:  c.fraction,
+				})}),
+				logger: rec,
+				trace:  traceID(testProjectID, "abcdef0123456789"),
+			}
+
+			lg.Debug("debug")
+			lg.Info("info")
+			lg.Warning("warning")
+			lg.Error("error")
+
+			if !reflect.DeepEqual(rec.payloads, c.want) {
+				t.Errorf("Expected %v, got %v", c.want, rec.payloads)
+			}
+		})
+	}
+}
+
+func TestParseSeveritySampling(t *testing.T) {
+	got, err := parseSeveritySampling("INFO:0.1, debug:0.01")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[Severity]float64{SeverityInfo: 0.1, SeverityDebug: 0.01}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	for _, s := range []string{"INFO", "INFO:1.5", "INFO:-0.1", "INFO:x", "VERBOSE:0.5"} {
+		if _, err := parseSeveritySampling(s); err == nil {
+			t.Errorf("Expected an error parsing %q", s)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package gaelog
+
+import (
+	"context"
+
+	"cloud.google.com/go/logging"
+)
+
+// A Sink receives the entries logged by a Logger created with WithSink. *logging.Logger
+// implements Sink. If a Sink also has a method Flush() error then it is called when the Logger is
+// flushed.
+type Sink interface {
+	Log(e logging.Entry)
+}
+
+// WithSink makes the Logger pass its entries to s rather than sending them to Stackdriver
+// Logging. No Stackdriver Logging client is created. Entries are complete, with the trace, span,
+// resource, and labels set, so this suits tests that assert on what a handler logs as well as
+// adapting gaelog to other logging backends.
+func WithSink(s Sink) Option {
+	return func(cfg *config) {
+		cfg.sink = s
+	}
+}
+
+// sinkLogger is an entryLogger that passes entries to a Sink.
+type sinkLogger struct {
+	sink Sink
+}
+
+func (l sinkLogger) Log(e logging.Entry) {
+	l.sink.Log(e)
+}
+
+// LogSync passes the entry to the Sink, which is as synchronous as a Sink can be.
+func (l sinkLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	l.sink.Log(e)
+	return nil
+}
+
+// Flush flushes the Sink if it has a Flush method, as *logging.Logger does. Otherwise it is a
+// no-op because Sinks are responsible for their own buffering.
+func (l sinkLogger) Flush() error {
+	if f, ok := l.sink.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package gaelog
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithSlowRequestThreshold makes a wrapped handler log an entry at warning severity when a request
+// takes longer than d to complete. The entry includes the elapsed time and details of the request
+// and response. It has no effect on Loggers created with New and its variants.
+func WithSlowRequestThreshold(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.slowRequestThreshold = d
+	}
+}
+
+// logSlowRequest logs an entry describing a request that took longer than the threshold.
+func (lg *Logger) logSlowRequest(s requestSummary, threshold time.Duration) {
+	message := fmt.Sprintf("Slow request: %s %s took %v, exceeding threshold of %v", s.r.Method, s.r.URL.Path, s.latency, threshold)
+	if lg.logger == nil {
+		lg.Log(SeverityWarning, message)
+		return
+	}
+
+	e := lg.entry(SeverityWarning, message)
+	e.HTTPRequest = s.httpRequest()
+	lg.write(e)
+}
@@ -0,0 +1,63 @@
+package gaelog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestWithSlowRequestThreshold(t *testing.T) {
+	setGAEEnvVars(t)
+
+	cases := []struct {
+		name      string
+		sleep     time.Duration
+		expectLog bool
+	}{
+		{"slow", 20 * time.Millisecond, true},
+		{"fast", 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(c.sleep)
+				w.WriteHeader(http.StatusTeapot)
+			}), WithSlowRequestThreshold(10*time.Millisecond), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+			req := httptest.NewRequest("GET", "http://example.com/slow", nil)
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := decodeEntries(t, &buf)
+			if !c.expectLog {
+				if len(entries) != 0 {
+					t.Errorf("Expected no entries, got %v", entries)
+				}
+				return
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(entries))
+			}
+
+			e := entries[0]
+			if e["severity"] != "WARNING" {
+				t.Errorf("Expected severity WARNING, got %v", e["severity"])
+			}
+			if msg, _ := e["message"].(string); !strings.HasPrefix(msg, "Slow request: GET /slow took") {
+				t.Errorf("Unexpected message %q", msg)
+			}
+			httpRequest, _ := e["httpRequest"].(map[string]interface{})
+			if httpRequest["status"] != float64(http.StatusTeapot) {
+				t.Errorf("Expected status %d, got %v", http.StatusTeapot, httpRequest["status"])
+			}
+		})
+	}
+}
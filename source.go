@@ -0,0 +1,81 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// sourceLocation returns the source location of the caller depth frames above the caller of
+// sourceLocation, or nil if it cannot be determined.
+func sourceLocation(depth int) *logpb.LogEntrySourceLocation {
+	pc, file, line, ok := runtime.Caller(depth + 1)
+	if !ok {
+		return nil
+	}
+
+	loc := &logpb.LogEntrySourceLocation{
+		File: file,
+		Line: int64(line),
+	}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		loc.Function = fn.Name()
+	}
+	return loc
+}
+
+// logDepth logs payload with the source location of the caller depth frames above the caller of
+// logDepth, applying any entry defaults carried by ctx.
+func (lg *Logger) logDepth(ctx context.Context, severity Severity, depth int, payload interface{}) {
+	if lg.logger == nil {
+		log.Output(depth+2, fmt.Sprint(payload))
+		return
+	}
+
+	e := lg.entry(severity, payload)
+	e.SourceLocation = sourceLocation(depth + 1)
+	lg.writeContext(ctx, e)
+}
+
+// LogfDepth is like Logf but also sets the entry's source location to that of a caller up the
+// stack. A depth of 0 identifies the caller of LogfDepth, 1 the caller of that, and so on. This
+// is for helpers built atop gaelog, which can report the location of their callers rather than
+// their own.
+func (lg *Logger) LogfDepth(severity Severity, depth int, format string, v ...interface{}) {
+	msg := sprintf(format, v...)
+	lg.logDepth(context.Background(), severity, depth+1, msg)
+	lg.checkFormat(format, v, msg)
+}
+
+// LogDepth is like Log but also sets the entry's source location to that of a caller up the stack.
+// See LogfDepth for the meaning of depth.
+func (lg *Logger) LogDepth(severity Severity, depth int, v interface{}) {
+	lg.logDepth(context.Background(), severity, depth+1, v)
+}
+
+// LogfDepth calls LogfDepth on the Logger in ctx. This should be called from a handler that has
+// been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped
+// then messages are simply logged using the standard library's log package.
+func LogfDepth(ctx context.Context, severity Severity, depth int, format string, v ...interface{}) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	msg := sprintf(format, v...)
+	logger.logDepth(ctx, severity, depth+1, msg)
+	logger.checkFormat(format, v, msg)
+}
+
+// LogDepth calls LogDepth on the Logger in ctx. This should be called from a handler that has
+// been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped
+// then messages are simply logged using the standard library's log package.
+func LogDepth(ctx context.Context, severity Severity, depth int, v interface{}) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	logger.logDepth(ctx, severity, depth+1, v)
+}
@@ -0,0 +1,50 @@
+package gaelog
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// logHelper is a logging helper like one that might be built atop gaelog.
+func logHelper(lg *Logger, msg string) {
+	lg.LogfDepth(SeverityInfo, 1, "helper: %s", msg)
+}
+
+func TestLogfDepth(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	logHelper(lg, "hi") // This line is the expected source location.
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	loc, _ := entries[0]["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if file, _ := loc["file"].(string); !strings.HasSuffix(file, "source_test.go") {
+		t.Errorf("Expected file to be source_test.go, got %v", loc)
+	}
+	if fn, _ := loc["function"].(string); !strings.HasSuffix(fn, "TestLogfDepth") {
+		t.Errorf("Expected function to be TestLogfDepth, got %v", loc)
+	}
+}
+
+func TestLogDepthFallback(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	lg := &Logger{}
+	lg.LogDepth(SeverityInfo, 0, "hi")
+
+	if !strings.HasPrefix(buf.String(), "source_test.go:") {
+		t.Errorf("Expected the caller's location, got %q", buf.String())
+	}
+}
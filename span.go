@@ -0,0 +1,75 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// A Span describes the work done by a Logger between its creation and its closing. Spans are only
+// created when the WithSpans option is given.
+type Span struct {
+	// ProjectID is the ID of the project that the trace belongs to.
+	ProjectID string
+
+	// TraceID is the bare trace ID (not the full resource name) taken from the request.
+	TraceID string
+
+	// SpanID is the 16-character hex ID generated for this span.
+	SpanID string
+
+	// ParentSpanID is the 16-character hex ID of the span that the inbound request was made
+	// under, or empty if the request did not carry one.
+	ParentSpanID string
+
+	// Name is the display name of the span. It is the request's URL path.
+	Name string
+
+	Start time.Time
+	End   time.Time
+
+	// Status is the HTTP status code of the response. It is only set for the Loggers of wrapped
+	// handlers, and is 0 otherwise or if the connection was hijacked.
+	Status int
+}
+
+// A SpanWriter writes spans to a tracing backend such as Cloud Trace. gaelog does not depend on
+// any particular tracing client; implement this interface to adapt one.
+type SpanWriter interface {
+	WriteSpan(ctx context.Context, s Span) error
+}
+
+// WithSpans makes the Logger generate a new span ID, a child of the span given in the request's
+// trace context (if any), and set it on every entry it logs. All entries logged
+// for a request thus share a span.
+func WithSpans() Option {
+	return func(cfg *config) {
+		cfg.spans = true
+	}
+}
+
+// WithSpanWriter implies WithSpans and additionally writes the span to w when the Logger is
+// closed. This is optional; logs are correlated by span whether or not the span is written.
+func WithSpanWriter(w SpanWriter) Option {
+	return func(cfg *config) {
+		cfg.spans = true
+		cfg.spanWriter = w
+	}
+}
+
+// newSpanID returns a random 16-character hex span ID.
+func newSpanID() string {
+	return RandomIDGenerator.NewID(8)
+}
+
+// hexSpanID converts a span ID as given in the X-Cloud-Trace-Context header, which is a decimal
+// unsigned 64-bit integer, to the 16-character hex form used by Cloud Trace. It returns the empty
+// string if s is not a valid span ID.
+func hexSpanID(s string) string {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%016x", n)
+}
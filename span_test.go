@@ -0,0 +1,87 @@
+package gaelog
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+type recordingSpanWriter struct {
+	spans []Span
+}
+
+func (w *recordingSpanWriter) WriteSpan(ctx context.Context, s Span) error {
+	w.spans = append(w.spans, s)
+	return nil
+}
+
+func TestNewSpanID(t *testing.T) {
+	re := regexp.MustCompile("^[0-9a-f]{16}$")
+
+	a := newSpanID()
+	b := newSpanID()
+	if !re.MatchString(a) {
+		t.Errorf("Expected 16 hex characters, got %q", a)
+	}
+	if a == b {
+		t.Errorf("Expected distinct span IDs, got %q twice", a)
+	}
+}
+
+func TestHexSpanID(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"1", "0000000000000001"},
+		{"255", "00000000000000ff"},
+		{"18446744073709551615", "ffffffffffffffff"},
+		{"0", ""},
+		{"", ""},
+		{"abc", ""},
+	}
+
+	for _, c := range cases {
+		if got := hexSpanID(c.in); got != c.want {
+			t.Errorf("hexSpanID(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSpanWriter(t *testing.T) {
+	unset := setEnvVars(map[string]string{
+		"GOOGLE_CLOUD_PROJECT": testProjectID,
+		"GAE_SERVICE":          testServiceID,
+		"GAE_VERSION":          testVersionID,
+	})
+	defer unset()
+
+	r := httptest.NewRequest("GET", "https://example.com/foo", nil)
+	r.Header.Set(traceContextHeaderName, "abcdef0123456789/255;o=1")
+
+	sw := &recordingSpanWriter{}
+	lg, err := NewWithOptions(r, WithSpanWriter(sw))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lg.spanID == "" {
+		t.Errorf("Expected span ID to be set")
+	}
+
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(sw.spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(sw.spans))
+	}
+
+	s := sw.spans[0]
+	if s.TraceID != "abcdef0123456789" || s.SpanID != lg.spanID || s.ParentSpanID != "00000000000000ff" || s.Name != "/foo" {
+		t.Errorf("Unexpected span: %+v", s)
+	}
+	if s.End.Before(s.Start) {
+		t.Errorf("Expected span end %v to be after start %v", s.End, s.Start)
+	}
+}
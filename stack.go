@@ -0,0 +1,43 @@
+package gaelog
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+)
+
+// LogStack logs msg along with the stack trace of the calling goroutine, for seeing how an
+// unexpected code path was reached without treating it as an error. The entry's payload has the
+// fields "message", which is msg, and "stack", which is the stack trace in the format of
+// runtime/debug.Stack. Unlike ReportError, it does not report to Error Reporting, and the entry may
+// be logged at any severity.
+func (lg *Logger) LogStack(severity Severity, msg string) {
+	lg.logStack(context.Background(), severity, msg)
+}
+
+// LogStack calls LogStack on the Logger in ctx. This should be called from a handler that has
+// been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped
+// then the message and stack trace are simply logged using the standard library's log package.
+func LogStack(ctx context.Context, severity Severity, msg string) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
+		logger = &Logger{}
+	}
+	logger.logStack(ctx, severity, msg)
+}
+
+// logStack logs msg and the current stack trace with the source location of the caller of its
+// caller.
+func (lg *Logger) logStack(ctx context.Context, severity Severity, msg string) {
+	stack := string(debug.Stack())
+
+	if lg.logger == nil {
+		log.Output(3, msg+"\n\n"+stack)
+		return
+	}
+
+	lg.logDepth(ctx, severity, 2, map[string]interface{}{
+		"message": msg,
+		"stack":   stack,
+	})
+}
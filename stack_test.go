@@ -0,0 +1,50 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogStack(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	lg.LogStack(SeverityDebug, "how did we get here?")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	if payload["message"] != "how did we get here?" {
+		t.Errorf("Unexpected message: %v", payload["message"])
+	}
+	if stack, _ := payload["stack"].(string); !strings.Contains(stack, "TestLogStack") {
+		t.Errorf("Expected the stack to include the caller, got %q", stack)
+	}
+
+	loc, _ := entries[0]["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if fn, _ := loc["function"].(string); !strings.HasSuffix(fn, "TestLogStack") {
+		t.Errorf("Expected function to be TestLogStack, got %v", loc)
+	}
+}
+
+func TestLogStackFallback(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	LogStack(context.Background(), SeverityInfo, "hi")
+
+	if !strings.HasPrefix(buf.String(), "stack_test.go:") || !strings.Contains(buf.String(), "TestLogStackFallback") {
+		t.Errorf("Expected the caller's location and stack, got %q", buf.String())
+	}
+}
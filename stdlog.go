@@ -0,0 +1,112 @@
+package gaelog
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// WithStdLogBridge makes a wrapped handler capture the lines that it logs with the standard
+// library's log package, directly or through a dependency, and log them as entries correlated with
+// the request, with SeverityDefault, rather than letting them bypass gaelog.
+//
+// The log package's output is global to the process, so it can't simply be redirected for the
+// duration of a request. Instead, when a request is handled, the log package's output is replaced
+// with a bridge, if it isn't already, that sends each line to the Logger of the request being
+// handled by the goroutine that logged it, and writes the lines of other goroutines to the previous
+// output as before. This has consequences to be aware of:
+//
+//   - Only lines logged by the handler's own goroutine are captured; those logged by goroutines it
+//     starts go to the previous output.
+//   - The bridge stays in place once installed. If the app later calls log.SetOutput then lines
+//     aren't captured until the next request is handled, which reinstalls the bridge atop the new
+//     output.
+//   - Each line costs a lookup of the calling goroutine's ID, which is parsed from a stack trace,
+//     and a lock shared by all goroutines. This is fine for occasional lines but not for heavy
+//     logging through the log package.
+//
+// Lines keep the prefix and flags of the log package, so consider log.SetFlags(0) to drop the
+// timestamp, which entries have anyway. It has no effect on Loggers in fallback mode, which
+// themselves log using the log package, nor on Loggers created with New and its variants.
+func WithStdLogBridge() Option {
+	return func(cfg *config) {
+		cfg.stdLogBridge = true
+	}
+}
+
+// stdLogBridge is the bridge installed as the log package's output by WithStdLogBridge.
+var stdLogBridge = struct {
+	mu sync.Mutex
+
+	// out is the log package's output before the bridge was installed.
+	out io.Writer
+
+	// loggers are the Loggers of the requests being handled, by the ID of the goroutine handling
+	// them.
+	loggers map[string]*Logger
+
+	// writing is the IDs of the goroutines whose lines are being sent to a Logger, so that lines
+	// the Logger itself logs with the log package, e.g. if its client fails, aren't sent back to
+	// it.
+	writing map[string]bool
+}{
+	loggers: make(map[string]*Logger),
+	writing: make(map[string]bool),
+}
+
+// bridgeStdLog sends the lines logged with the log package by the calling goroutine to lg until
+// the returned function is called.
+func (lg *Logger) bridgeStdLog() (stop func()) {
+	id := goroutineID()
+	if id == "" {
+		return func() {}
+	}
+
+	stdLogBridge.mu.Lock()
+	if w := log.Writer(); w != (stdLogWriter{}) {
+		stdLogBridge.out = w
+		log.SetOutput(stdLogWriter{})
+	}
+	stdLogBridge.loggers[id] = lg
+	stdLogBridge.mu.Unlock()
+
+	return func() {
+		stdLogBridge.mu.Lock()
+		delete(stdLogBridge.loggers, id)
+		stdLogBridge.mu.Unlock()
+	}
+}
+
+// stdLogWriter is the io.Writer set as the log package's output by the bridge.
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	id := goroutineID()
+
+	stdLogBridge.mu.Lock()
+	lg := stdLogBridge.loggers[id]
+	if lg != nil {
+		if stdLogBridge.writing[id] {
+			lg = nil
+		} else {
+			stdLogBridge.writing[id] = true
+		}
+	}
+	out := stdLogBridge.out
+	stdLogBridge.mu.Unlock()
+
+	if lg == nil {
+		return out.Write(p)
+	}
+
+	defer func() {
+		stdLogBridge.mu.Lock()
+		delete(stdLogBridge.writing, id)
+		stdLogBridge.mu.Unlock()
+	}()
+
+	// The log package writes one line at a time, ending in a newline.
+	lg.Log(SeverityDefault, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,51 @@
+package gaelog
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestWithStdLogBridge(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Print("from the handler")
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			log.Print("from another goroutine")
+		}()
+		<-done
+	}), WithStdLogBridge(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	log.Print("after the request")
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	if e := rec.entries[0]; e.Payload != "from the handler" || e.Trace == "" {
+		t.Errorf("Expected the handler's line as a correlated entry, got %+v", e)
+	}
+
+	if want := "from another goroutine\nafter the request\n"; buf.String() != want {
+		t.Errorf("Expected other lines to go to the previous output %q, got %q", want, buf.String())
+	}
+}
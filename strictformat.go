@@ -0,0 +1,77 @@
+package gaelog
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// WithStrictFormat makes the Logger check the messages of Logf and the like for the markers that
+// the fmt package leaves in place of bad verbs and mismatched arguments, such as "%!d(string=x)".
+// For each message that has them, it logs an additional entry with SeverityWarning whose source
+// location is that of the bad call and whose payload has the fields "message", which describes the
+// problem, and "format", which is the format string, so that such bugs don't go unnoticed. Messages
+// are not checked if the format or a string argument itself contains "%!", which would make the
+// check ambiguous.
+func WithStrictFormat() Option {
+	return func(cfg *config) {
+		cfg.strictFormat = true
+	}
+}
+
+// badFormat reports whether msg, formatted from format and v, shows signs of a formatting error.
+func badFormat(format string, v []interface{}, msg string) bool {
+	if !strings.Contains(msg, "%!") || strings.Contains(format, "%!") {
+		return false
+	}
+	for _, arg := range v {
+		if s, ok := arg.(string); ok && strings.Contains(s, "%!") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFormat logs a warning if the Logger was created with WithStrictFormat and msg, formatted
+// from format and v, shows signs of a formatting error. Loggers in fallback mode don't check.
+func (lg *Logger) checkFormat(format string, v []interface{}, msg string) {
+	if lg.logger == nil || !lg.config().strictFormat || !badFormat(format, v, msg) {
+		return
+	}
+
+	e := lg.entry(SeverityWarning, map[string]interface{}{
+		"message": fmt.Sprintf("gaelog: bad format call: %q produced %q", format, msg),
+		"format":  format,
+	})
+	e.SourceLocation = outsideCaller()
+	lg.write(e)
+}
+
+// packageDir is the directory of the package's source files.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// outsideCaller returns the source location of the innermost caller outside of the package, or
+// nil if it cannot be determined. The package's tests count as outside of it.
+func outsideCaller() *logpb.LogEntrySourceLocation {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if filepath.Dir(f.File) != packageDir || strings.HasSuffix(f.File, "_test.go") {
+			return &logpb.LogEntrySourceLocation{
+				File:     f.File,
+				Line:     int64(f.Line),
+				Function: f.Function,
+			}
+		}
+		if !more {
+			return nil
+		}
+	}
+}
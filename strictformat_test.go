@@ -0,0 +1,53 @@
+package gaelog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithStrictFormat(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithStrictFormat())
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+
+	// The argument is an interface so that vet doesn't catch the mismatch.
+	var x interface{} = "x"
+	lg.Infof("%d items", x)
+	Infof(ctx, "%d%% done", 50)
+	Infof(ctx, "got %s", "%!d(string=x)")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 4 {
+		t.Fatalf("Expected 3 entries and a warning, got %d", len(entries))
+	}
+
+	warning := entries[1]
+	if warning["severity"] != "WARNING" {
+		t.Errorf("Expected severity WARNING, got %v", warning["severity"])
+	}
+	payload, _ := warning["message"].(map[string]interface{})
+	if msg, _ := payload["message"].(string); !strings.Contains(msg, "%!d(string=x)") || payload["format"] != "%d items" {
+		t.Errorf("Unexpected warning payload: %v", payload)
+	}
+	loc, _ := warning["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if fn, _ := loc["function"].(string); !strings.HasSuffix(fn, "TestWithStrictFormat") {
+		t.Errorf("Expected function to be TestWithStrictFormat, got %v", loc)
+	}
+
+	for _, e := range entries[2:] {
+		if e["severity"] == "WARNING" {
+			t.Errorf("Expected no warning for a correct format call, got %v", e)
+		}
+	}
+}
+
+func TestStrictFormatOff(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	var x interface{} = "x"
+	lg.Infof("%d items", x)
+
+	if entries := decodeEntries(t, buf); len(entries) != 1 {
+		t.Errorf("Expected no warning without WithStrictFormat, got %d entries", len(entries))
+	}
+}
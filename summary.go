@@ -0,0 +1,331 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// A SummaryFormat is a format of the entry logged by WithRequestSummary. Formats may be combined
+// with bitwise OR to log the summary in more than one format.
+type SummaryFormat int
+
+const (
+	// SummaryStructured logs the summary with the entry's HTTPRequest field set, which the Logs
+	// Explorer displays much like App Engine's own request logs.
+	SummaryStructured SummaryFormat = 1 << iota
+
+	// SummaryCombined logs the summary as a text line in the Combined Log Format used by Apache
+	// and other servers, for tooling that ingests such access logs.
+	SummaryCombined
+)
+
+// combinedLogTimeFormat is the time format used in the Combined Log Format.
+const combinedLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// WithRequestSummary makes a wrapped handler log an entry summarizing each request when it
+// completes, including the status, request and response sizes, and latency. It is logged in the
+// SummaryStructured format, at info severity unless the status is an error; see
+// WithSummarySeverity, WithStatusSeverityFunc, and EscalateSummary. If the route that matched the
+// request is known (see Route) then the entry's payload is an object whose "message" field is the
+// summary and whose "method" and "route" fields are the request's method and the route's pattern.
+// It has no effect on Loggers created with New and its variants.
+func WithRequestSummary() Option {
+	return WithSummaryFormat(SummaryStructured)
+}
+
+// WithSummaryFormat is like WithRequestSummary but logs the summary in the given format(s).
+func WithSummaryFormat(f SummaryFormat) Option {
+	return func(cfg *config) {
+		cfg.summaryFormat = f
+	}
+}
+
+// WithSampledSummaryOnly makes a wrapped handler log the entry of WithRequestSummary only for
+// requests whose trace is sampled, per the trace context's sampling flag (o=1 in the
+// X-Cloud-Trace-Context header), keeping the volume of access logs in proportion to that of traces.
+// Of the requests whose trace isn't sampled, the given fraction, from 0 to 1, are summarized
+// anyway; which ones depends only on the trace ID, so the decision is the same on every instance.
+// Other entries, such as those of WithSlowRequestThreshold, are unaffected.
+func WithSampledSummaryOnly(fraction float64) Option {
+	return func(cfg *config) {
+		cfg.sampledSummaryOnly = true
+		cfg.summaryFraction = fraction
+	}
+}
+
+// summarySampled reports whether the summary of the Logger's request is logged, per
+// WithSampledSummaryOnly.
+func (lg *Logger) summarySampled() bool {
+	cfg := lg.config()
+	return !cfg.sampledSummaryOnly || lg.sampled || traceSampled(lg.trace, cfg.summaryFraction)
+}
+
+// WithSummarySeverity sets the base severity of the entry logged by WithRequestSummary. If this
+// option is not given then SeverityInfo is used. The entry is logged at the greater of the base
+// severity and the severity returned by the status severity function; see WithStatusSeverityFunc.
+func WithSummarySeverity(severity Severity) Option {
+	return func(cfg *config) {
+		cfg.summarySeverity = severity
+	}
+}
+
+// EscalateSummary raises the severity of the entry that WithRequestSummary logs when the request
+// completes to at least the given severity, such as when the handler recovers from an error that
+// the response's status doesn't reflect. It never lowers the severity. Loggers derived from the
+// request's Logger, such as by WithDerivedLogger and Detach, escalate the same summary.
+func (lg *Logger) EscalateSummary(severity Severity) {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if severity > s.summaryFloor {
+		s.summaryFloor = severity
+	}
+}
+
+// EscalateSummary calls EscalateSummary on the Logger in ctx. This should be called from a handler
+// that has been wrapped with Wrap or WrapWithID. If it is called from a handler that has not been
+// wrapped then it does nothing.
+func EscalateSummary(ctx context.Context, severity Severity) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.EscalateSummary(severity)
+	}
+}
+
+// escalateSummary returns the greater of severity and that given to EscalateSummary.
+func (lg *Logger) escalateSummary(severity Severity) Severity {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summaryFloor > severity {
+		return s.summaryFloor
+	}
+	return severity
+}
+
+// WithStatusSeverityFunc sets the function that escalates the severity of the entry logged by
+// WithRequestSummary according to the response's status code. The entry is logged at the greater
+// of the severity returned by fn and the base severity set with WithSummarySeverity, so fn may
+// return SeverityDefault for statuses that shouldn't escalate it. If this option is not given then
+// DefaultStatusSeverity is used.
+func WithStatusSeverityFunc(fn func(status int) Severity) Option {
+	return func(cfg *config) {
+		cfg.statusSeverity = fn
+	}
+}
+
+// DefaultStatusSeverity escalates the severity of request summaries as App Engine does for its own
+// request logs: responses with a 4xx status are logged at warning severity and those with a 5xx
+// status at error severity. It returns SeverityDefault, which doesn't escalate the severity, for
+// all other statuses.
+func DefaultStatusSeverity(status int) Severity {
+	switch {
+	case status >= 500:
+		return SeverityError
+	case status >= 400:
+		return SeverityWarning
+	}
+	return SeverityDefault
+}
+
+// severityForStatus returns the severity at which to log the summary of a request whose response had
+// the given status.
+func (cfg *config) severityForStatus(status int) Severity {
+	statusSeverity := DefaultStatusSeverity
+	if cfg.statusSeverity != nil {
+		statusSeverity = cfg.statusSeverity
+	}
+
+	if sev := statusSeverity(status); sev > cfg.summarySeverity {
+		return sev
+	}
+	return cfg.summarySeverity
+}
+
+// sensitiveHeaders are headers that WithLoggedHeaders never logs because they carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+// WithLoggedHeaders adds the given request and response headers to the entry logged by
+// WithRequestSummary in the SummaryStructured format. The entry's payload becomes an object whose
+// "message" field is the usual summary message and whose "requestHeaders" and "responseHeaders"
+// fields hold those of the headers that were present. Headers that carry credentials, such as
+// Authorization and Cookie, are never logged, even if listed.
+func WithLoggedHeaders(allow []string) Option {
+	return func(cfg *config) {
+		cfg.loggedHeaders = nil
+		for _, h := range allow {
+			h = http.CanonicalHeaderKey(h)
+			if !sensitiveHeaders[h] {
+				cfg.loggedHeaders = append(cfg.loggedHeaders, h)
+			}
+		}
+	}
+}
+
+// requestSummary describes a completed request.
+type requestSummary struct {
+	r       *http.Request
+	w       *responseWriter
+	start   time.Time
+	latency time.Duration
+
+	// route is the pattern of the route that matched the request, if known.
+	route string
+
+	// breakdown is only set if the Logger was created with WithLatencyBreakdown.
+	breakdown *latencyBreakdown
+
+	// body counts the bytes read from the request's body. It is nil if the request had no body.
+	body *requestBody
+}
+
+// requestSize returns the size of the request's body: its Content-Length if known, otherwise the
+// number of bytes the handler read from it. A chunked body that the handler didn't read in full
+// is thus undercounted, and one that it didn't read at all has size 0, which is omitted.
+func (s requestSummary) requestSize() int64 {
+	size := s.r.ContentLength
+	if s.body != nil && s.body.n > size {
+		size = s.body.n
+	}
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+// remoteHost returns the host part of the request's remote address.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func (s requestSummary) httpRequest() *logging.HTTPRequest {
+	return &logging.HTTPRequest{
+		Request:      s.r,
+		Status:       s.w.statusCode(),
+		RequestSize:  s.requestSize(),
+		ResponseSize: s.w.size,
+		Latency:      s.latency,
+		RemoteIP:     remoteHost(s.r),
+	}
+}
+
+// headerFields returns the values of the given headers that are present in h, with multiple
+// values joined by commas, or nil if none are.
+func headerFields(h http.Header, names []string) map[string]string {
+	var fields map[string]string
+	for _, name := range names {
+		if values := h.Values(name); len(values) > 0 {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[name] = strings.Join(values, ", ")
+		}
+	}
+	return fields
+}
+
+// structuredPayload returns the payload of the entry logged in the SummaryStructured format.
+func (s requestSummary) structuredPayload(loggedHeaders []string) interface{} {
+	message := fmt.Sprintf("%s %s %d", s.r.Method, s.r.URL.Path, s.w.statusCode())
+	if len(loggedHeaders) == 0 && s.route == "" && s.breakdown == nil {
+		return message
+	}
+
+	payload := map[string]interface{}{"message": message}
+	if s.route != "" {
+		payload["method"] = s.r.Method
+		payload["route"] = s.route
+	}
+	if s.breakdown != nil {
+		payload["latency"] = s.breakdown.fields()
+	}
+	if fields := headerFields(s.r.Header, loggedHeaders); fields != nil {
+		payload["requestHeaders"] = fields
+	}
+	if fields := headerFields(s.w.Header(), loggedHeaders); fields != nil {
+		payload["responseHeaders"] = fields
+	}
+	return payload
+}
+
+// combined returns the summary as a line in the Combined Log Format.
+func (s requestSummary) combined() string {
+	user := "-"
+	if u, _, ok := s.r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+
+	size := "-"
+	if s.w.size > 0 {
+		size = strconv.FormatInt(s.w.size, 10)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q",
+		remoteHost(s.r),
+		user,
+		s.start.Format(combinedLogTimeFormat),
+		fmt.Sprintf("%s %s %s", s.r.Method, s.r.RequestURI, s.r.Proto),
+		s.w.statusCode(),
+		size,
+		s.r.Referer(),
+		s.r.UserAgent())
+}
+
+// logSummary logs the request summary in the configured formats.
+func (lg *Logger) logSummary(s requestSummary) {
+	cfg := lg.config()
+	format := cfg.summaryFormat
+	severity := lg.escalateSummary(cfg.severityForStatus(s.w.statusCode()))
+
+	if format&SummaryStructured != 0 {
+		payload := s.structuredPayload(cfg.loggedHeaders)
+		if lg.logger == nil {
+			lg.Log(severity, payload)
+		} else {
+			e := lg.entry(severity, payload)
+			e.HTTPRequest = s.httpRequest()
+			lg.write(e)
+		}
+	}
+
+	if format&SummaryCombined != 0 {
+		lg.Log(severity, s.combined())
+	}
+}
+
+// logHijacked logs an entry in place of the summary of a request whose connection was hijacked,
+// such as for a WebSocket upgrade.
+func (lg *Logger) logHijacked(s requestSummary) {
+	message := fmt.Sprintf("%s %s: connection hijacked", s.r.Method, s.r.URL.Path)
+	if upgrade := s.r.Header.Get("Upgrade"); upgrade != "" {
+		message = fmt.Sprintf("%s %s: connection upgraded to %s", s.r.Method, s.r.URL.Path, upgrade)
+	}
+
+	severity := lg.escalateSummary(lg.config().summarySeverity)
+
+	if lg.logger == nil {
+		lg.Log(severity, message)
+		return
+	}
+
+	e := lg.entry(severity, message)
+	e.HTTPRequest = &logging.HTTPRequest{
+		Request:  s.r,
+		RemoteIP: remoteHost(s.r),
+	}
+	lg.write(e)
+}
@@ -0,0 +1,324 @@
+package gaelog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestRequestSummaryCombined(t *testing.T) {
+	r := httptest.NewRequest("GET", "/foo?bar=baz", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.SetBasicAuth("frank", "secret")
+	r.Header.Set("Referer", "http://example.com/start")
+	r.Header.Set("User-Agent", "Mozilla/4.08")
+
+	w := newResponseWriter(httptest.NewRecorder())
+	w.Write([]byte("hello"))
+
+	s := requestSummary{
+		r:     r,
+		w:     w,
+		start: time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC),
+	}
+
+	want := `192.0.2.1 - frank [10/Oct/2000:20:55:36 +0000] "GET /foo?bar=baz HTTP/1.1" 200 5 "http://example.com/start" "Mozilla/4.08"`
+	if got := s.combined(); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestWithSummaryFormat(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not here"))
+	}), WithSummaryFormat(SummaryStructured|SummaryCombined), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+	req := httptest.NewRequest("GET", "http://example.com/missing", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	structured := entries[0]
+	if structured["message"] != "GET /missing 404" {
+		t.Errorf("Unexpected message %v", structured["message"])
+	}
+	if structured["severity"] != "WARNING" {
+		t.Errorf("Expected a 404 to be logged at warning severity, got %v", structured["severity"])
+	}
+	httpRequest, _ := structured["httpRequest"].(map[string]interface{})
+	if httpRequest["status"] != float64(http.StatusNotFound) || fmt.Sprint(httpRequest["responseSize"]) != "8" {
+		t.Errorf("Unexpected httpRequest %v", httpRequest)
+	}
+
+	if _, ok := entries[1]["httpRequest"]; ok {
+		t.Errorf("Expected Combined Log Format entry to be text only, got %v", entries[1])
+	}
+}
+
+func TestSummarySeverity(t *testing.T) {
+	escalateAll := func(status int) Severity {
+		if status >= 300 {
+			return SeverityCritical
+		}
+		return SeverityDefault
+	}
+
+	cases := []struct {
+		name    string
+		options []Option
+		status  int
+		want    Severity
+	}{
+		{"default_ok", nil, http.StatusOK, SeverityInfo},
+		{"default_redirect", nil, http.StatusFound, SeverityInfo},
+		{"default_client_error", nil, http.StatusNotFound, SeverityWarning},
+		{"default_server_error", nil, http.StatusServiceUnavailable, SeverityError},
+		{"debug_base_ok", []Option{WithSummarySeverity(SeverityDebug)}, http.StatusOK, SeverityDebug},
+		{"debug_base_server_error", []Option{WithSummarySeverity(SeverityDebug)}, http.StatusInternalServerError, SeverityError},
+		{"notice_base_client_error", []Option{WithSummarySeverity(SeverityNotice)}, http.StatusBadRequest, SeverityWarning},
+		{"error_base_client_error", []Option{WithSummarySeverity(SeverityError)}, http.StatusBadRequest, SeverityError},
+		{"func_redirect", []Option{WithStatusSeverityFunc(escalateAll)}, http.StatusFound, SeverityCritical},
+		{"func_ok", []Option{WithStatusSeverityFunc(escalateAll)}, http.StatusOK, SeverityInfo},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := newConfig(c.options).severityForStatus(c.status); got != c.want {
+				t.Errorf("Expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestWithLoggedHeaders(t *testing.T) {
+	cfg := newConfig([]Option{WithLoggedHeaders([]string{"x-request-id", "Authorization", "cookie", "Content-Type", "Accept"})})
+
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "session=secret")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+
+	w := newResponseWriter(httptest.NewRecorder())
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+
+	s := requestSummary{r: r, w: w}
+
+	want := map[string]interface{}{
+		"message": "GET /foo 200",
+		"requestHeaders": map[string]string{
+			"X-Request-Id": "abc",
+			"Accept":       "text/html, application/json",
+		},
+		"responseHeaders": map[string]string{
+			"Content-Type": "text/plain",
+		},
+	}
+	if diff := pretty.Compare(want, s.structuredPayload(cfg.loggedHeaders)); diff != "" {
+		t.Errorf("Unexpected payload. Diff (-want +got):\n%s", diff)
+	}
+
+	if got := s.structuredPayload(nil); got != "GET /foo 200" {
+		t.Errorf("Expected a text payload without logged headers, got %v", got)
+	}
+}
+
+func TestStructuredPayloadRoute(t *testing.T) {
+	w := newResponseWriter(httptest.NewRecorder())
+	w.WriteHeader(http.StatusNotFound)
+	s := requestSummary{r: httptest.NewRequest("DELETE", "/users/42", nil), w: w, route: "/users/{id}"}
+
+	want := map[string]interface{}{
+		"message": "DELETE /users/42 404",
+		"method":  "DELETE",
+		"route":   "/users/{id}",
+	}
+	if diff := pretty.Compare(want, s.structuredPayload(nil)); diff != "" {
+		t.Errorf("Unexpected payload. Diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequestSummaryHijacked(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var buf bytes.Buffer
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		conn.Close()
+	}), WithRequestSummary(), WithSlowRequestThreshold(time.Nanosecond), WithLoggerOptions(logging.RedirectAsJSON(&buf)))
+
+	req := httptest.NewRequest("GET", "http://example.com/ws", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	req.Header.Set("Upgrade", "websocket")
+	handler.ServeHTTP(hijackableRecorder{httptest.NewRecorder()}, req)
+
+	entries := decodeEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected only the hijack entry, got %v", entries)
+	}
+	if want := "GET /ws: connection upgraded to websocket"; entries[0]["message"] != want {
+		t.Errorf("Expected message %q, got %v", want, entries[0]["message"])
+	}
+}
+
+func TestWithSampledSummaryOnly(t *testing.T) {
+	setGAEEnvVars(t)
+
+	cases := []struct {
+		name     string
+		header   string
+		fraction float64
+		want     int
+	}{
+		{"sampled", "abcdef0123456789/123;o=1", 0, 1},
+		{"unsampled", "abcdef0123456789/123;o=0", 0, 0},
+		{"unsampled_within_fraction", "abcdef0123456789/123;o=0", 1, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := &entryRecorder{}
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+				WithRequestSummary(), WithSampledSummaryOnly(c.fraction), WithSink(rec))
+
+			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+			req.Header.Set(traceContextHeaderName, c.header)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(rec.entries) != c.want {
+				t.Errorf("Expected %d summary entries, got %d", c.want, len(rec.entries))
+			}
+		})
+	}
+}
+
+func TestRequestSummarySizes(t *testing.T) {
+	cases := []struct {
+		name          string
+		body          string
+		contentLength int64
+		read          bool
+		want          int64
+	}{
+		{"content_length_unread", "hello world", 11, false, 11},
+		{"content_length_read", "hello world", 11, true, 11},
+		{"chunked_read", "hello world", -1, true, 11},
+		{"chunked_unread", "hello world", -1, false, 0},
+		{"no_body", "", 0, false, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+
+			rec := &entryRecorder{}
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c.read {
+					io.ReadAll(r.Body)
+				}
+				w.Write([]byte("hello"))
+			}), WithRequestSummary(), WithSink(rec))
+
+			var body io.Reader
+			if c.body != "" {
+				body = strings.NewReader(c.body)
+			}
+			req := httptest.NewRequest("POST", "http://example.com", body)
+			req.ContentLength = c.contentLength
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(rec.entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+			}
+			hr := rec.entries[0].HTTPRequest
+			if hr.RequestSize != c.want {
+				t.Errorf("Expected request size %d, got %d", c.want, hr.RequestSize)
+			}
+			if hr.ResponseSize != 5 {
+				t.Errorf("Expected response size 5, got %d", hr.ResponseSize)
+			}
+		})
+	}
+}
+
+func TestEscalateSummary(t *testing.T) {
+	cases := []struct {
+		name     string
+		escalate []Severity
+		status   int
+		want     Severity
+	}{
+		{"none", nil, http.StatusOK, SeverityInfo},
+		{"raised", []Severity{SeverityWarning}, http.StatusOK, SeverityWarning},
+		{"highest", []Severity{SeverityError, SeverityWarning}, http.StatusOK, SeverityError},
+		{"never_lowered", []Severity{SeverityDebug}, http.StatusNotFound, SeverityWarning},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+
+			rec := &entryRecorder{}
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, sev := range c.escalate {
+					EscalateSummary(r.Context(), sev)
+				}
+				w.WriteHeader(c.status)
+			}), WithRequestSummary(), WithSink(rec))
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(rec.entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+			}
+			if got := rec.entries[0].Severity; got != c.want {
+				t.Errorf("Expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestEscalateSummaryDerived(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithDerivedLogger(r.Context(), WithLabels(map[string]string{"tenant": "acme"}))
+		EscalateSummary(ctx, SeverityError)
+	}), WithRequestSummary(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+	if got := rec.entries[0].Severity; got != SeverityError {
+		t.Errorf("Expected %v, got %v", SeverityError, got)
+	}
+}
@@ -0,0 +1,161 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithSyncSeverity makes the Logger write entries of at least the given severity synchronously,
+// blocking until Stackdriver Logging has accepted them, rather than buffering them to be sent in
+// the background. This ensures that critical entries are not lost if the instance is shut down
+// before the buffer is flushed, at the cost of latency for each such call. Errors writing the
+// entries are reported to the error handler (see WithErrorHandler).
+func WithSyncSeverity(severity Severity) Option {
+	return func(cfg *config) {
+		cfg.syncWrites = true
+		cfg.syncSeverity = severity
+	}
+}
+
+// WithWriteTimeout bounds how long a synchronous write (see WithSyncSeverity) may block so that a
+// slow or unavailable logging backend cannot hang request handling indefinitely. A write that times
+// out is abandoned and the resulting error is reported to the error handler. By default there is
+// no timeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.writeTimeout = d
+	}
+}
+
+// WithSyncAll makes the Logger flush after every entry it logs, so that each entry has been sent
+// to Stackdriver Logging by the time the logging call returns. This is intended for integration
+// tests that read entries back from Cloud Logging or an emulator (see WithEmulatorHost), which
+// would otherwise race with the background sending of entries. It is not intended for production
+// use, as flushing after every entry is slow. Errors flushing are reported to the error handler
+// (see WithErrorHandler).
+func WithSyncAll() Option {
+	return func(cfg *config) {
+		cfg.syncAll = true
+	}
+}
+
+// writesSync reports whether entries of the given severity are written synchronously.
+func (cfg *config) writesSync(severity Severity) bool {
+	return cfg.syncWrites && severity >= cfg.syncSeverity
+}
+
+// writeSync sends the entry to Stackdriver Logging synchronously, subject to the write timeout.
+// The Logger must not be in fallback mode.
+func (lg *Logger) writeSync(e logging.Entry) {
+	cfg := lg.config()
+
+	ctx := context.Background()
+	if cfg.writeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.writeTimeout)
+		defer cancel()
+	}
+
+	if err := lg.logger.LogSync(ctx, e); err != nil {
+		lg.writeError(err)
+	}
+}
+
+// flushAfterWrite flushes the Logger if it was created with WithSyncAll.
+func (lg *Logger) flushAfterWrite() {
+	cfg := lg.config()
+	if !cfg.syncAll {
+		return
+	}
+	if err := lg.logger.Flush(); err != nil {
+		lg.writeError(err)
+	}
+}
+
+// writeError reports an error writing the Logger's entries to the error handler and records it to
+// be returned by Sync.
+func (lg *Logger) writeError(err error) {
+	lg.mu.Lock()
+	lg.writeErrs = append(lg.writeErrs, err)
+	lg.mu.Unlock()
+
+	lg.config().handleClientError(err)
+}
+
+// Sync flushes the Logger's buffered entries, blocking until they have been written, and returns
+// an error if any of the entries logged since the Logger was created or Sync was last called could
+// not be written. It is like zap's Sync, for use before a batch job or other process exits, when
+// entries that could not be written should be noticed rather than only reported to the error
+// handler. The Logger remains usable afterward. In fallback mode it does nothing and returns nil.
+//
+// Loggers that share their Stackdriver Logging client with others, such as those of a Handler's
+// requests, may also return errors writing the others' entries.
+func (lg *Logger) Sync() error {
+	if lg.logger == nil {
+		return nil
+	}
+
+	flushErr := lg.logger.Flush()
+
+	lg.mu.Lock()
+	errs := lg.writeErrs
+	lg.writeErrs = nil
+	lg.mu.Unlock()
+
+	if flushErr != nil {
+		errs = append(errs, flushErr)
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return &SyncError{Errs: errs}
+}
+
+// A SyncError is returned by Sync when more than one error occurred writing entries.
+type SyncError struct {
+	Errs []error
+}
+
+func (e *SyncError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("gaelog: %d errors writing entries: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors, for use with errors.Is and errors.As as of Go 1.20.
+func (e *SyncError) Unwrap() []error {
+	return e.Errs
+}
+
+// Is reports whether any of the errors matches target, so that errors.Is looks through them
+// before Go 1.20 too.
+func (e *SyncError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target, so that errors.As looks through them
+// before Go 1.20 too.
+func (e *SyncError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
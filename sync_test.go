@@ -0,0 +1,176 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// blockingLogger is an entryLogger whose synchronous writes block until their context is done,
+// like those to an unresponsive backend.
+type blockingLogger struct {
+	logged []logging.Entry
+}
+
+func (l *blockingLogger) Log(e logging.Entry) {
+	l.logged = append(l.logged, e)
+}
+
+func (l *blockingLogger) LogSync(ctx context.Context, e logging.Entry) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (l *blockingLogger) Flush() error {
+	return nil
+}
+
+func TestWithWriteTimeout(t *testing.T) {
+	var reported error
+	bl := &blockingLogger{}
+	lg := &Logger{
+		cfg: newConfig([]Option{
+			WithSyncSeverity(SeverityError),
+			WithWriteTimeout(10 * time.Millisecond),
+			WithErrorHandler(func(err error) { reported = err }),
+		}),
+		logger: bl,
+	}
+
+	lg.Info("buffered")
+	if len(bl.logged) != 1 {
+		t.Errorf("Expected the info entry to be buffered, got %d buffered entries", len(bl.logged))
+	}
+
+	lg.Error("synchronous")
+	if len(bl.logged) != 1 {
+		t.Errorf("Expected the error entry to be written synchronously, got %d buffered entries", len(bl.logged))
+	}
+	if !errors.Is(reported, context.DeadlineExceeded) {
+		t.Errorf("Expected the timeout to be reported, got %v", reported)
+	}
+}
+
+func TestWithSyncSeverity(t *testing.T) {
+	cfg := newConfig([]Option{WithSyncSeverity(SeverityCritical)})
+
+	cases := []struct {
+		severity Severity
+		want     bool
+	}{
+		{SeverityDefault, false},
+		{SeverityError, false},
+		{SeverityCritical, true},
+		{SeverityEmergency, true},
+	}
+	for _, c := range cases {
+		if got := cfg.writesSync(c.severity); got != c.want {
+			t.Errorf("writesSync(%v) = %v, want %v", c.severity, got, c.want)
+		}
+	}
+
+	if defaultConfig.writesSync(SeverityEmergency) {
+		t.Error("Expected no entries to be written synchronously by default")
+	}
+}
+
+// countingLogger is an entryLogger that counts calls to Flush.
+type countingLogger struct {
+	nopLogger
+	flushes int
+}
+
+func (l *countingLogger) Flush() error {
+	l.flushes++
+	return nil
+}
+
+func TestWithSyncAll(t *testing.T) {
+	cl := &countingLogger{}
+	lg := &Logger{cfg: newConfig([]Option{WithSyncAll()}), logger: cl}
+
+	lg.Info("one")
+	lg.Infof("%s", "two")
+	if cl.flushes != 2 {
+		t.Errorf("Expected a flush after each entry, got %d flushes", cl.flushes)
+	}
+
+	cl = &countingLogger{}
+	lg = &Logger{cfg: defaultConfig, logger: cl}
+	lg.Info("one")
+	if cl.flushes != 0 {
+		t.Errorf("Expected no flushes by default, got %d", cl.flushes)
+	}
+}
+
+func TestSync(t *testing.T) {
+	lg := &Logger{cfg: defaultConfig, logger: &countingLogger{}}
+	if err := lg.Sync(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if flushes := lg.logger.(*countingLogger).flushes; flushes != 1 {
+		t.Errorf("Expected Sync to flush, got %d flushes", flushes)
+	}
+
+	if err := (&Logger{}).Sync(); err != nil {
+		t.Errorf("Expected no error in fallback mode, got %v", err)
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	var reported []error
+	lg := &Logger{
+		cfg: newConfig([]Option{
+			WithSyncSeverity(SeverityError),
+			WithWriteTimeout(time.Millisecond),
+			WithErrorHandler(func(err error) { reported = append(reported, err) }),
+		}),
+		logger: &blockingLogger{},
+	}
+
+	lg.Error("times out")
+	if err := lg.Sync(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if len(reported) != 1 {
+		t.Errorf("Expected the write error to be reported to the error handler too, got %v", reported)
+	}
+
+	if err := lg.Sync(); err != nil {
+		t.Errorf("Expected errors to be returned only once, got %v", err)
+	}
+
+	lg.logger = failingLogger{}
+	lg.writeError(errors.New("write failed"))
+	err := lg.Sync()
+
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) || len(syncErr.Errs) != 2 {
+		t.Fatalf("Expected a *SyncError with 2 errors, got %v", err)
+	}
+	if want := "gaelog: 2 errors writing entries: write failed; flush failed"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestSyncErrorIsAs(t *testing.T) {
+	partial := &PartialWriteError{Err: errors.New("some entries are invalid")}
+	err := &SyncError{Errs: []error{context.DeadlineExceeded, partial}}
+
+	// Call the methods directly so that they're tested whatever the Go version's errors package
+	// makes of Unwrap.
+	if !err.Is(context.DeadlineExceeded) {
+		t.Errorf("Expected Is to match %v", context.DeadlineExceeded)
+	}
+	if err.Is(context.Canceled) {
+		t.Errorf("Expected Is not to match %v", context.Canceled)
+	}
+
+	var got *PartialWriteError
+	if !err.As(&got) || got != partial {
+		t.Errorf("Expected As to find %v, got %v", partial, got)
+	}
+}
@@ -0,0 +1,90 @@
+package gaelog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithRequestTail makes the Logger keep the last n entries it writes in memory, in addition to
+// sending them as usual, for a debug endpoint or a post-mortem to show what the request was doing.
+// RequestTail returns them. If the Logger was also created with WithRecoverPanics then the entry
+// logged for a panic includes them in its "recent_entries" field. Memory use is bounded by n;
+// older entries are discarded.
+func WithRequestTail(n int) Option {
+	return func(cfg *config) {
+		cfg.requestTail = n
+	}
+}
+
+// entryTail is a ring buffer of the last entries written by a Logger.
+type entryTail struct {
+	mu      sync.Mutex
+	entries []logging.Entry
+	next    int
+	full    bool
+}
+
+func newEntryTail(n int) *entryTail {
+	return &entryTail{entries: make([]logging.Entry, n)}
+}
+
+func (t *entryTail) add(e logging.Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries[t.next] = e
+	t.next++
+	if t.next == len(t.entries) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// snapshot returns a copy of the entries in the order in which they were written.
+func (t *entryTail) snapshot() []logging.Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.full {
+		return append([]logging.Entry(nil), t.entries[:t.next]...)
+	}
+	return append(append([]logging.Entry(nil), t.entries[t.next:]...), t.entries[:t.next]...)
+}
+
+// RequestTail returns the last entries written by the Logger in ctx, oldest first, as kept by
+// WithRequestTail. It returns nil if ctx has no Logger or the Logger keeps no entries.
+func RequestTail(ctx context.Context) []logging.Entry {
+	logger := loggerFromContext(ctx)
+	if logger == nil || logger.tail == nil {
+		return nil
+	}
+	return logger.tail.snapshot()
+}
+
+// recentEntry is a summary of an entry kept by WithRequestTail, as included in a panic's entry.
+type recentEntry struct {
+	Time     string      `json:"time"`
+	Severity string      `json:"severity"`
+	Payload  interface{} `json:"payload"`
+}
+
+// recentEntries returns summaries of the entries kept by WithRequestTail, or nil if there are none.
+func (lg *Logger) recentEntries() []recentEntry {
+	if lg.tail == nil {
+		return nil
+	}
+
+	var recent []recentEntry
+	for _, e := range lg.tail.snapshot() {
+		recent = append(recent, recentEntry{
+			Time:     e.Timestamp.Format(time.RFC3339Nano),
+			Severity: strings.ToUpper(e.Severity.String()),
+			Payload:  e.Payload,
+		})
+	}
+	return recent
+}
@@ -0,0 +1,81 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestEntryTail(t *testing.T) {
+	tail := newEntryTail(3)
+	for i := 0; i < 5; i++ {
+		tail.add(logging.Entry{Payload: fmt.Sprint(i)})
+	}
+
+	var payloads []interface{}
+	for _, e := range tail.snapshot() {
+		payloads = append(payloads, e.Payload)
+	}
+
+	want := []interface{}{"2", "3", "4"}
+	if !reflect.DeepEqual(payloads, want) {
+		t.Errorf("Expected %v, got %v", want, payloads)
+	}
+}
+
+func TestRequestTail(t *testing.T) {
+	setGAEEnvVars(t)
+
+	var got []interface{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			Infof(r.Context(), "step %d", i)
+		}
+		for _, e := range RequestTail(r.Context()) {
+			got = append(got, e.Payload)
+		}
+	}), WithRequestTail(2), WithSink(nopLogger{}))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := []interface{}{"step 2", "step 3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if tail := RequestTail(context.Background()); tail != nil {
+		t.Errorf("Expected no entries without a Logger, got %v", tail)
+	}
+}
+
+func TestRequestTailPanic(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Infof(r.Context(), "about to fail")
+		panic("oh no")
+	}), WithRequestTail(5), WithRecoverPanics(), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(rec.entries))
+	}
+	event, ok := rec.entries[1].Payload.(panicEvent)
+	if !ok {
+		t.Fatalf("Expected a panic event, got %T", rec.entries[1].Payload)
+	}
+	if len(event.Recent) != 1 || event.Recent[0].Payload != "about to fail" || event.Recent[0].Severity != "INFO" {
+		t.Errorf("Expected the entry before the panic to be included, got %+v", event.Recent)
+	}
+}
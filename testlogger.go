@@ -0,0 +1,57 @@
+package gaelog
+
+import (
+	"io"
+)
+
+// testLoggerProjectID is the project ID of Loggers made by NewTestLogger unless another is set
+// with WithProjectID.
+const testLoggerProjectID = "test-project"
+
+// NewTestLogger returns a Logger that writes entries to w as lines of JSON in the structured
+// logging format of GCPEncoder, for unit tests of code that logs, which can then assert on what
+// was written. No environment variables or request are needed and no Stackdriver Logging client is
+// created. Another Encoder may be given with WithEncoder. The Logger's entries have no trace unless
+// one is set with WithTestTrace.
+func NewTestLogger(w io.Writer, options ...Option) *Logger {
+	cfg := newConfig(options)
+	if cfg.encoder == nil {
+		cfg.encoder = GCPEncoder
+	}
+	cfg.output = w
+	cfg.splitOutput = nil
+
+	lg := &Logger{
+		cfg:    cfg,
+		logger: newEncoderLogger(cfg),
+		monRes: cfg.resource,
+		labels: cfg.labels,
+	}
+
+	if t := cfg.testTrace; t != nil {
+		projectID := cfg.projectID
+		if projectID == "" {
+			projectID = testLoggerProjectID
+		}
+		lg.trace = traceID(projectID, t.trace)
+		lg.spanID = t.spanID
+		lg.sampled = t.sampled
+	}
+	return lg
+}
+
+// WithTestTrace sets the trace of the entries of a Logger made by NewTestLogger, as if it had been
+// created for a request with the given trace context. The trace is qualified with the project ID
+// set with WithProjectID, or "test-project" if none is. It has no effect on other Loggers.
+func WithTestTrace(trace, spanID string, sampled bool) Option {
+	return func(cfg *config) {
+		cfg.testTrace = &testTrace{trace: trace, spanID: spanID, sampled: sampled}
+	}
+}
+
+// testTrace holds the settings given to WithTestTrace.
+type testTrace struct {
+	trace   string
+	spanID  string
+	sampled bool
+}
@@ -0,0 +1,62 @@
+package gaelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	lg := NewTestLogger(&buf, WithTestTrace("abcdef0123456789", "000000000000007b", true))
+
+	lg.Infof("hello %s", "test")
+	lg.Warning(map[string]interface{}{"message": "careful", "count": 2})
+	if err := lg.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	var entries []map[string]interface{}
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		entries = append(entries, m)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0]["message"] != "hello test" || entries[0]["severity"] != "INFO" {
+		t.Errorf("Unexpected entry: %v", entries[0])
+	}
+	if entries[1]["message"] != "careful" || entries[1]["count"] != 2.0 || entries[1]["severity"] != "WARNING" {
+		t.Errorf("Unexpected entry: %v", entries[1])
+	}
+	for _, e := range entries {
+		if e["logging.googleapis.com/trace"] != "projects/test-project/traces/abcdef0123456789" ||
+			e["logging.googleapis.com/spanId"] != "000000000000007b" ||
+			e["logging.googleapis.com/trace_sampled"] != true {
+			t.Errorf("Expected the test trace, got %v", e)
+		}
+	}
+}
+
+func TestNewTestLoggerNoTrace(t *testing.T) {
+	var buf bytes.Buffer
+	lg := NewTestLogger(&buf, WithProjectID("other-project"))
+	lg.Info("hi")
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := m["logging.googleapis.com/trace"]; ok {
+		t.Errorf("Expected no trace, got %v", m)
+	}
+	if lg.IsFallback() {
+		t.Errorf("Expected the test Logger not to be in fallback mode")
+	}
+}
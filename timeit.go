@@ -0,0 +1,39 @@
+package gaelog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Timeit returns a function that logs how long it has been since Timeit was called, for timing a
+// block of code in one line:
+//
+//	defer gaelog.Timeit(ctx, gaelog.SeverityDebug, "fetch inventory")()
+//
+// The entry's payload has the fields "message", e.g. "fetch inventory took 12.5ms", "name", which
+// is name, and "duration", which is the elapsed time formatted as seconds with a trailing "s", e.g.
+// "0.012500000s", for use in queries. Its source location is that of the returned function's
+// caller. This should be called from a handler that has been wrapped with Wrap or WrapWithID. If it
+// is called from a handler that has not been wrapped then the message is simply logged using the
+// standard library's log package.
+func Timeit(ctx context.Context, severity Severity, name string) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		message := fmt.Sprintf("%s took %v", name, elapsed)
+
+		logger := loggerFromContext(ctx)
+		if logger == nil || logger.logger == nil {
+			log.Output(2, message)
+			return
+		}
+
+		logger.logDepth(ctx, severity, 1, map[string]interface{}{
+			"message":  message,
+			"name":     name,
+			"duration": formatDuration(elapsed),
+		})
+	}
+}
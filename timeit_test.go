@@ -0,0 +1,61 @@
+package gaelog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeit(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+
+	done := Timeit(ctx, SeverityDebug, "fetch inventory")
+	time.Sleep(2 * time.Millisecond)
+	done()
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0]["severity"] != "DEBUG" {
+		t.Errorf("Expected severity DEBUG, got %v", entries[0]["severity"])
+	}
+
+	payload, _ := entries[0]["message"].(map[string]interface{})
+	if msg, _ := payload["message"].(string); !strings.HasPrefix(msg, "fetch inventory took ") {
+		t.Errorf("Unexpected message: %q", msg)
+	}
+	if payload["name"] != "fetch inventory" {
+		t.Errorf("Unexpected name: %v", payload["name"])
+	}
+	d, err := time.ParseDuration(payload["duration"].(string))
+	if err != nil || d < 2*time.Millisecond {
+		t.Errorf("Expected a duration of at least 2ms, got %v (%v)", payload["duration"], err)
+	}
+
+	loc, _ := entries[0]["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if fn, _ := loc["function"].(string); !strings.HasSuffix(fn, "TestTimeit") {
+		t.Errorf("Expected function to be TestTimeit, got %v", loc)
+	}
+}
+
+func TestTimeitFallback(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	Timeit(context.Background(), SeverityInfo, "work")()
+
+	if got := buf.String(); !strings.HasPrefix(got, "timeit_test.go:") || !strings.Contains(got, "work took ") {
+		t.Errorf("Expected the caller's location and the elapsed time, got %q", got)
+	}
+}
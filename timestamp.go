@@ -0,0 +1,15 @@
+package gaelog
+
+import (
+	"time"
+)
+
+// WithTimestampPrecision truncates the timestamps of the Logger's entries to a multiple of d, such
+// as time.Millisecond, for sinks and exporters that can't handle nanosecond timestamps. It also
+// makes the timestamps written by Encoders shorter. If this option is not given, or d is not
+// positive, then timestamps have full precision.
+func WithTimestampPrecision(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timestampPrecision = d
+	}
+}
@@ -0,0 +1,26 @@
+package gaelog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTimestampPrecision(t *testing.T) {
+	rec := &entryRecorder{}
+	lg := &Logger{cfg: newConfig([]Option{WithTimestampPrecision(time.Millisecond)}), logger: sinkLogger{rec}}
+
+	e := lg.entry(SeverityInfo, "hi")
+	e.Timestamp = time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	lg.write(e)
+
+	if want := time.Date(2020, 1, 2, 3, 4, 5, 123000000, time.UTC); !rec.entries[0].Timestamp.Equal(want) {
+		t.Errorf("Expected timestamp %v, got %v", want, rec.entries[0].Timestamp)
+	}
+
+	rec = &entryRecorder{}
+	lg = &Logger{cfg: defaultConfig, logger: sinkLogger{rec}}
+	lg.write(e)
+	if !rec.entries[0].Timestamp.Equal(e.Timestamp) {
+		t.Errorf("Expected full precision by default, got %v", rec.entries[0].Timestamp)
+	}
+}
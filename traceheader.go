@@ -0,0 +1,28 @@
+package gaelog
+
+import (
+	"net/http"
+)
+
+// WithTraceResponseHeader makes a wrapped handler set the response header with the given name,
+// such as "X-Trace-Id", to the ID of the request's trace, for pasting into the Logs Explorer when
+// debugging. The header is set before the handler is called, so it is sent even if the handler
+// writes its response straight away, and the handler may override or delete it. Nothing is set
+// for requests without a trace context (see WithTracePropagators). It has no effect on Loggers
+// created with New and its variants.
+func WithTraceResponseHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.traceResponseHeader = name
+	}
+}
+
+// setTraceResponseHeader sets the header configured with WithTraceResponseHeader, if any.
+func (cfg *config) setTraceResponseHeader(w http.ResponseWriter, r *http.Request) {
+	if cfg.traceResponseHeader == "" {
+		return
+	}
+
+	if trace, _, _ := cfg.extractTrace(r); trace != "" {
+		w.Header().Set(cfg.traceResponseHeader, trace)
+	}
+}
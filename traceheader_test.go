@@ -0,0 +1,41 @@
+package gaelog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithTraceResponseHeader(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"trace", "abcdef0123456789/123;o=1", "abcdef0123456789"},
+		{"trace_only", "abcdef0123456789", "abcdef0123456789"},
+		{"no_header", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setGAEEnvVars(t)
+
+			handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Write the response straight away; the header must already be set.
+				w.Write([]byte("hello"))
+			}), WithTraceResponseHeader("X-Trace-Id"), WithSink(nopLogger{}))
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			if c.header != "" {
+				req.Header.Set(traceContextHeaderName, c.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Result().Header.Get("X-Trace-Id"); got != c.want {
+				t.Errorf("Expected header %q, got %q", c.want, got)
+			}
+		})
+	}
+}
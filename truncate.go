@@ -0,0 +1,104 @@
+package gaelog
+
+import (
+	"encoding/json"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxPayloadSize is a payload size limit suitable for use with WithMaxPayloadSize. Cloud
+	// Logging rejects entries larger than 256 KiB; this leaves room for the entry's other fields.
+	DefaultMaxPayloadSize = 250 * 1024
+
+	// TruncatedLabel is the key of the label set on entries whose payload was truncated because it
+	// exceeded the limit set with WithMaxPayloadSize.
+	TruncatedLabel = "gaelog_truncated"
+
+	// truncationMarker is appended to truncated strings.
+	truncationMarker = "…"
+)
+
+// WithMaxPayloadSize limits the size of entries' payloads to n bytes, as marshaled to JSON. Cloud
+// Logging rejects entries that are too large (see DefaultMaxPayloadSize), and they are otherwise lost
+// silently. String payloads over the limit are truncated, with an ellipsis marking the truncation.
+// Object payloads over the limit have their largest fields trimmed (if they are strings) or dropped
+// until they fit. Truncated entries have the label TruncatedLabel. A limit of 0, the default,
+// disables the check.
+func WithMaxPayloadSize(n int) Option {
+	return func(cfg *config) {
+		cfg.maxPayloadSize = n
+	}
+}
+
+// truncateString truncates s to at most n bytes, including the truncation marker, without
+// splitting a UTF-8 sequence.
+func truncateString(s string, n int) string {
+	n -= len(truncationMarker)
+	if n <= 0 {
+		return truncationMarker
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + truncationMarker
+}
+
+// truncatePayload returns payload, reduced to fit within limit bytes when marshaled to JSON, and
+// whether it had to be reduced.
+func truncatePayload(payload interface{}, limit int) (interface{}, bool) {
+	if s, ok := payload.(string); ok {
+		if len(s) <= limit {
+			return payload, false
+		}
+		return truncateString(s, limit), true
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil || len(b) <= limit {
+		// If it can't be marshaled then leave it to the logging client to report.
+		return payload, false
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return payload, false
+	}
+
+	// Trim the largest fields first since that loses the fewest fields.
+	sizes := make(map[string]int, len(fields))
+	keys := make([]string, 0, len(fields))
+	for k, v := range fields {
+		fb, _ := json.Marshal(v)
+		sizes[k] = len(fb)
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	excess := len(b) - limit
+	for _, k := range keys {
+		if excess <= 0 {
+			break
+		}
+
+		if s, ok := fields[k].(string); ok && len(s) > excess+len(truncationMarker) {
+			// JSON escaping means the marshaled string may be longer than the string itself,
+			// so the excess is only approximately removed; the loop continues if need be.
+			fields[k] = truncateString(s, len(s)-excess)
+			nb, _ := json.Marshal(fields[k])
+			excess -= sizes[k] - len(nb)
+			continue
+		}
+
+		delete(fields, k)
+		// Account for the key, its quotes, the colon, and the comma.
+		excess -= sizes[k] + len(k) + 4
+	}
+
+	return fields, true
+}
@@ -0,0 +1,84 @@
+package gaelog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateString(t *testing.T) {
+	cases := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello world", 8, "hello" + truncationMarker},
+		{"héllo", 5, "h" + truncationMarker},
+		{"hello", 2, truncationMarker},
+	}
+
+	for _, c := range cases {
+		got := truncateString(c.s, c.n)
+		if got != c.want {
+			t.Errorf("truncateString(%q, %d) = %q, want %q", c.s, c.n, got, c.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateString(%q, %d) = %q is not valid UTF-8", c.s, c.n, got)
+		}
+	}
+}
+
+func TestTruncatePayload(t *testing.T) {
+	if _, truncated := truncatePayload("short", 100); truncated {
+		t.Errorf("Expected short string not to be truncated")
+	}
+
+	got, truncated := truncatePayload(strings.Repeat("a", 200), 100)
+	if !truncated || len(got.(string)) > 100 {
+		t.Errorf("Expected string to be truncated to 100 bytes, got %d bytes", len(got.(string)))
+	}
+
+	payload := map[string]interface{}{
+		"big":   strings.Repeat("b", 500),
+		"list":  []int{1, 2, 3},
+		"small": "s",
+	}
+	got, truncated = truncatePayload(payload, 200)
+	if !truncated {
+		t.Fatalf("Expected object to be truncated")
+	}
+
+	b, _ := json.Marshal(got)
+	if len(b) > 200 {
+		t.Errorf("Expected at most 200 bytes, got %d: %s", len(b), b)
+	}
+
+	fields := got.(map[string]interface{})
+	if fields["small"] != "s" {
+		t.Errorf("Expected small field to be kept, got %v", fields)
+	}
+	if s, _ := fields["big"].(string); !strings.HasSuffix(s, truncationMarker) {
+		t.Errorf("Expected big field to be trimmed, got %v", fields["big"])
+	}
+}
+
+func TestWithMaxPayloadSize(t *testing.T) {
+	lg, buf := newRedirectedLogger(t, WithMaxPayloadSize(10))
+
+	lg.Info("this is too long")
+	lg.Info("short")
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	labels, _ := entries[0]["logging.googleapis.com/labels"].(map[string]interface{})
+	if entries[0]["message"] != "this is"+truncationMarker || labels[TruncatedLabel] != "true" {
+		t.Errorf("Expected truncated entry, got %v", entries[0])
+	}
+	if _, ok := entries[1]["logging.googleapis.com/labels"]; ok || entries[1]["message"] != "short" {
+		t.Errorf("Expected untruncated entry, got %v", entries[1])
+	}
+}
@@ -0,0 +1,60 @@
+package gaelog
+
+import (
+	"context"
+)
+
+// SetUser sets the user on whose behalf the request is being handled, such as a user ID or session
+// ID. Error Reporting counts the users affected by an error using this, so it is included in
+// errors reported with ReportError and in the payloads of entries logged at error severity or
+// above: a string payload becomes an object with the string as its "message" field, and an object
+// payload gains a "context" field unless it already has one. Entries are unaffected if no user
+// is set. The user is shared with the Loggers derived from the Logger with WithDerivedLogger,
+// Named, and Detach, whichever of them it is set with.
+func (lg *Logger) SetUser(user string) {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// currentUser returns the user set with SetUser.
+func (lg *Logger) currentUser() string {
+	s := lg.requestState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.user
+}
+
+// withUser returns payload with the user added to its error context. Payloads that are not a
+// string or a JSON object are returned unchanged.
+func withUser(payload interface{}, user string) interface{} {
+	switch p := payload.(type) {
+	case errorEvent:
+		// Reported errors set their own context.
+		return p
+	case string:
+		return map[string]interface{}{
+			"message": p,
+			"context": errorContext{User: user},
+		}
+	}
+
+	fields, err := payloadFields(payload)
+	if err != nil || fields == nil {
+		return payload
+	}
+	if _, ok := fields["context"]; !ok {
+		fields["context"] = errorContext{User: user}
+	}
+	return fields
+}
+
+// SetUser calls SetUser on the Logger in ctx. This should be called from a handler that has been
+// wrapped with Wrap or WrapWithID. If it is called from a handler that has not been wrapped then
+// it does nothing.
+func SetUser(ctx context.Context, user string) {
+	if logger := loggerFromContext(ctx); logger != nil {
+		logger.SetUser(user)
+	}
+}
@@ -0,0 +1,76 @@
+package gaelog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestSetUser(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	SetUser(ctx, "alice")
+
+	Info(ctx, "not an error")
+	Error(ctx, "an error")
+	Error(ctx, map[string]interface{}{"code": 7})
+	ReportError(ctx, errors.New("reported"))
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+
+	if entries[0]["message"] != "not an error" {
+		t.Errorf("Expected the info entry to be unchanged, got %v", entries[0]["message"])
+	}
+
+	wantUser := map[string]interface{}{"user": "alice"}
+	want := map[string]interface{}{"message": "an error", "context": wantUser}
+	if diff := pretty.Compare(want, entries[1]["message"]); diff != "" {
+		t.Errorf("Unexpected payload. Diff (-want +got):\n%s", diff)
+	}
+
+	want = map[string]interface{}{"code": float64(7), "context": wantUser}
+	if diff := pretty.Compare(want, entries[2]["message"]); diff != "" {
+		t.Errorf("Unexpected payload. Diff (-want +got):\n%s", diff)
+	}
+
+	reported, _ := entries[3]["message"].(map[string]interface{})
+	if diff := pretty.Compare(wantUser, reported["context"]); diff != "" {
+		t.Errorf("Unexpected reported error context. Diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetUserUnwrapped(t *testing.T) {
+	// Does nothing, and in particular doesn't panic.
+	SetUser(context.Background(), "alice")
+}
+
+func TestSetUserDerived(t *testing.T) {
+	lg, buf := newRedirectedLogger(t)
+
+	ctx := context.WithValue(context.Background(), ctxKey, lg)
+	SetUser(ctx, "alice")
+	derived := WithDerivedLogger(ctx)
+	Error(derived, "set on the parent")
+
+	SetUser(derived, "bob")
+	Error(ctx, "set on the derived Logger")
+	ReportError(ctx, errors.New("reported"))
+
+	entries := decodeEntries(t, buf)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"alice", "bob", "bob"} {
+		payload, _ := entries[i]["message"].(map[string]interface{})
+		wantContext := map[string]interface{}{"user": want}
+		if diff := pretty.Compare(wantContext, payload["context"]); diff != "" {
+			t.Errorf("Unexpected context of entry %d. Diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
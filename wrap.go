@@ -4,45 +4,152 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"cloud.google.com/go/logging"
 )
 
 type ctxKeyType string
 
-var ctxKey = ctxKeyType("gaelog-logger")
+// ctxKey is a constant, as are the package's other context keys, so that converting it to
+// interface{} to look up a value doesn't allocate.
+const ctxKey = ctxKeyType("gaelog-logger")
+
+// loggerFromContext returns the Logger stored in ctx by a wrapped handler, or nil if there is none.
+func loggerFromContext(ctx context.Context) *Logger {
+	logger, _ := ctx.Value(ctxKey).(*Logger)
+	return logger
+}
 
 // WrapWithID wraps a handler such that the request's context may be used to call the package-level logging functions.
 // See NewWithID for details on this function's arguments and how the logger is created.
 func WrapWithID(h http.Handler, logID string, options ...logging.LoggerOption) http.Handler {
+	return WrapWithOptions(h, WithLogID(logID), WithLoggerOptions(options...))
+}
+
+// Wrap is identical to WrapWithID with the exception that it uses the default log ID, which is
+// DefaultLogID unless another is set with Configure.
+func Wrap(h http.Handler, options ...logging.LoggerOption) http.Handler {
+	return WrapWithOptions(h, WithLoggerOptions(options...))
+}
+
+// WrapWithOptions is like WrapWithID but is configured using Options.
+// See NewWithOptions for details on how the logger is created.
+//
+// Options such as WithRequestSummary wrap the http.ResponseWriter given to h in order to record
+// the response's status and size. The wrapper implements http.Flusher, http.Hijacker, and
+// http.Pusher by forwarding to the original, so streaming responses work as before, including over
+// HTTP/2 and over h2c (see golang.org/x/net/http2/h2c) as used by gRPC-Web servers. With h2c,
+// pass the wrapped handler to h2c.NewHandler rather than wrapping the handler it returns, so that
+// each HTTP/2 request is wrapped rather than the connection as a whole.
+func WrapWithOptions(h http.Handler, options ...Option) http.Handler {
+	cfg := newConfig(options)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logger, _ := NewWithID(r, logID, options...)
+		start := time.Now()
+
+		logger, _ := newLogger(r, cfg)
 		defer logger.Close()
 
-		ctx := context.WithValue(r.Context(), ctxKey, logger)
-		h.ServeHTTP(w, r.WithContext(ctx))
+		serve(h, cfg, logger, start, w, r)
 	})
 }
 
-// Wrap is identical to WrapWithID with the exception that it uses the default log ID.
-func Wrap(h http.Handler, options ...logging.LoggerOption) http.Handler {
-	return WrapWithID(h, DefaultLogID, options...)
+// serve calls h with logger in the request's context and then logs what cfg calls for about the
+// request. start is when the request began to be handled.
+func serve(h http.Handler, cfg *config, logger *Logger, start time.Time, w http.ResponseWriter, r *http.Request) {
+	stop := logger.flushOnDone(r.Context())
+	defer stop()
+
+	if cfg.stdLogBridge && !logger.IsFallback() {
+		defer logger.bridgeStdLog()()
+	}
+
+	var rw *responseWriter
+	if cfg.wrapsResponseWriter() {
+		rw = newResponseWriter(w)
+		w = rw
+	}
+
+	cfg.setTraceResponseHeader(w, r)
+
+	ctx := logger.propagateCorrelationID(r.Context(), w, r)
+
+	// Find the route using the request that h sees, on which routers such as http.ServeMux record
+	// the route they match.
+	hr := r.WithContext(context.WithValue(ctx, ctxKey, logger))
+	logger.req = hr
+
+	var body *requestBody
+	if rw != nil {
+		body = wrapRequestBody(hr)
+	}
+
+	handlerStart := time.Now()
+	if cfg.recoverPanics {
+		logger.serveRecovering(h, rw, hr)
+	} else {
+		h.ServeHTTP(w, hr)
+	}
+	handlerLatency := time.Since(handlerStart)
+
+	summary := requestSummary{
+		r:       r,
+		w:       rw,
+		start:   start,
+		latency: time.Since(start),
+		route:   logger.route(),
+		body:    body,
+	}
+	if cfg.latencyBreakdown && rw != nil {
+		summary.breakdown = newLatencyBreakdown(start, summary.latency, handlerLatency, rw)
+	}
+
+	if rw != nil && rw.hijacked {
+		// The request's status and size are unknown, and its latency is that of the whole
+		// connection, so the usual entries would be misleading.
+		if cfg.summaryFormat != 0 && logger.summarySampled() {
+			logger.logHijacked(summary)
+		}
+		return
+	}
+
+	if logger.span != nil {
+		logger.span.Status = rw.statusCode()
+	}
+
+	if cfg.slowRequestThreshold > 0 && summary.latency > cfg.slowRequestThreshold {
+		logger.logSlowRequest(summary, cfg.slowRequestThreshold)
+	}
+
+	if cfg.summaryFormat != 0 && logger.summarySampled() {
+		logger.logSummary(summary)
+	}
+}
+
+// IsFallback calls IsFallback on the Logger in ctx. If ctx has no Logger, which is the case if the
+// handler was not wrapped with Wrap or WrapWithID, then logs go to the standard library's log
+// package and so it returns true.
+func IsFallback(ctx context.Context) bool {
+	logger := loggerFromContext(ctx)
+	return logger == nil || logger.IsFallback()
 }
 
 // Logf logs with the given severity. Remaining arguments are handled in the manner of fmt.Printf.
 // This should be called from a handler that has been wrapped with Wrap or WrapWithID. If it is
 // called from a handler that has not been wrapped then messages are simply logged using the standard
 // library's log package.
-func Logf(ctx context.Context, severity logging.Severity, format string, v ...interface{}) {
-	cv := ctx.Value(ctxKey)
-	if cv == nil {
+func Logf(ctx context.Context, severity Severity, format string, v ...interface{}) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
 		// No logger in the context, so the handler wasn't wrapped.
 		log.Printf(format, v...)
 		return
 	}
 
-	logger := cv.(*Logger)
-	logger.Logf(severity, format, v...)
+	msg := sprintf(format, v...)
+	logger.logContext(ctx, severity, msg)
+	logger.checkFormat(format, v, msg)
 }
 
 // Debugf calls Logf with debug severity.
@@ -90,16 +197,15 @@ func Emergencyf(ctx context.Context, format string, v ...interface{}) {
 // of JSON value). This should be called from a handler that has been wrapped with
 // Wrap or WrapWithID. If it is called from a handler that has not been wrapped
 // then messages are simply logged using the standard library's log package.
-func Log(ctx context.Context, severity logging.Severity, v interface{}) {
-	cv := ctx.Value(ctxKey)
-	if cv == nil {
+func Log(ctx context.Context, severity Severity, v interface{}) {
+	logger := loggerFromContext(ctx)
+	if logger == nil {
 		// No logger in the context, so the handler wasn't wrapped.
-		log.Print(v)
+		log.Print(defaultConfig.fallbackPayload(v))
 		return
 	}
 
-	logger := cv.(*Logger)
-	logger.Log(severity, v)
+	logger.logContext(ctx, severity, v)
 }
 
 // Debug calls Log with debug severity.
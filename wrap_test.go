@@ -1,11 +1,13 @@
 package gaelog
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"cloud.google.com/go/logging"
 	"github.com/kylelemons/godebug/pretty"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
@@ -57,3 +59,79 @@ func TestWrap(t *testing.T) {
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 }
+
+func TestIsFallback(t *testing.T) {
+	cases := []struct {
+		name           string
+		setEnv         bool
+		expectFallback bool
+	}{
+		{"fallback", false, true},
+		{"not_fallback", true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.setEnv {
+				setGAEEnvVars(t)
+			}
+
+			var got bool
+			handler := Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = IsFallback(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "http://example.com", nil)
+			req.Header.Set(traceContextHeaderName, "abcdef0123456789/abcdef")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != c.expectFallback {
+				t.Errorf("Expected IsFallback to be %v, got %v", c.expectFallback, got)
+			}
+		})
+	}
+
+	if !IsFallback(context.Background()) {
+		t.Errorf("Expected IsFallback to be true for a context without a Logger")
+	}
+}
+
+// entryRecorder is a Sink that records entries.
+type entryRecorder struct {
+	entries []logging.Entry
+}
+
+func (r *entryRecorder) Log(e logging.Entry) {
+	r.entries = append(r.entries, e)
+}
+
+func TestWrapCorrelation(t *testing.T) {
+	setGAEEnvVars(t)
+
+	rec := &entryRecorder{}
+	handler := WrapWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Infof(r.Context(), "hello %s", "world")
+	}), WithSink(rec))
+
+	req := httptest.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set(traceContextHeaderName, "abcdef0123456789/123;o=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(rec.entries))
+	}
+
+	e := rec.entries[0]
+	if e.Payload != "hello world" || e.Severity != SeverityInfo {
+		t.Errorf("Unexpected entry: %v", e)
+	}
+	if want := "projects/" + testProjectID + "/traces/abcdef0123456789"; e.Trace != want {
+		t.Errorf("Expected trace %q, got %q", want, e.Trace)
+	}
+	if want := "000000000000007b"; e.SpanID != want {
+		t.Errorf("Expected span ID %q, got %q", want, e.SpanID)
+	}
+	if !e.TraceSampled {
+		t.Error("Expected the entry to be marked as sampled")
+	}
+}